@@ -0,0 +1,478 @@
+// The public JSON API, served under '/api/v1' with CORS headers, so that browser tools on other
+// origins can use it too:
+//
+//   /api/v1/families                                    every module family
+//   /api/v1/families/{family}                           one family
+//   /api/v1/modules/{module}                            a module's versions, with file hashes
+//   /api/v1/modules/{module}/@v/{version}               one version of a module
+//   /api/v1/modules/{module}/@v/{version}/{file}        the source of one file
+//   /api/v1/openapi.json                                an OpenAPI description of all of the above
+//
+// where module is a module path, such as 'pkg.golang.fail/tuple/3/tuple' (the
+// 'pkg.golang.fail/' is optional), of a family in family::FAMILIES. Everything is computed from the
+// module templates rather than from repos, but that still renders and hashes every file of the
+// module's releases, which can be slow for large modules, so it's done on the generation pool (see
+// pool) rather than on the async runtime.
+
+use std::collections::BTreeMap;
+
+use axum::extract::Path;
+use axum::http::{Method, StatusCode};
+use axum::response::{IntoResponse, Response};
+use axum::routing::get;
+use axum::{Json, Router};
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+use tower_http::cors::{Any, CorsLayer};
+
+use crate::family::{self, Family, FAMILIES};
+use crate::{pool, RepoCommit};
+
+pub fn router() -> Router {
+    Router::new()
+        .route("/families", get(families))
+        .route("/families/:family", get(family))
+        .route("/modules/*path", get(module))
+        .route("/openapi.json", get(openapi))
+        .layer(
+            CorsLayer::new()
+                .allow_origin(Any)
+                .allow_methods([Method::GET]),
+        )
+}
+
+#[derive(Serialize)]
+struct FamilyJson {
+    name: &'static str,
+    description: &'static str,
+    page: &'static str,
+    module_path: String,
+    parameters: Vec<ParamJson>,
+    versions: Vec<FamilyVersionJson>,
+}
+
+#[derive(Serialize)]
+struct ParamJson {
+    name: &'static str,
+    minimum: family::Value,
+    maximum: family::Value,
+}
+
+#[derive(Serialize)]
+struct FamilyVersionJson {
+    version: &'static str,
+    module_path: String,
+}
+
+#[derive(Serialize)]
+struct ModuleJson {
+    module_path: String,
+    family: &'static str,
+    parameters: BTreeMap<&'static str, family::Value>,
+    latest: &'static str,
+    versions: Vec<VersionJson>,
+}
+
+#[derive(Serialize)]
+struct VersionJson {
+    version: &'static str,
+    time: String,
+    message: &'static str,
+    files: Vec<FileJson>,
+}
+
+#[derive(Serialize)]
+struct FileJson {
+    name: &'static str,
+    size: usize,
+    sha256: String,
+    url: String,
+}
+
+#[derive(Serialize)]
+struct SourceJson {
+    module_path: String,
+    version: &'static str,
+    name: &'static str,
+    size: usize,
+    sha256: String,
+    content: String,
+}
+
+fn family_json(family: &Family) -> FamilyJson {
+    let module_path = |major| format!("{}{}", family.path, family::module_path_suffix(major));
+    FamilyJson {
+        name: family.name,
+        description: family.description,
+        page: family.page,
+        module_path: module_path(family.majors().last().copied().unwrap_or(1)),
+        parameters: family
+            .params
+            .iter()
+            .map(|p| ParamJson {
+                name: p.name,
+                minimum: p.kind.minimum(),
+                maximum: p.kind.maximum(),
+            })
+            .collect(),
+        versions: (family.versions)()
+            .into_iter()
+            .map(|version| FamilyVersionJson {
+                version,
+                module_path: module_path(family::major(version)),
+            })
+            .collect(),
+    }
+}
+
+fn version_json(module_path: &str, commit: &RepoCommit) -> VersionJson {
+    VersionJson {
+        version: commit.version,
+        time: rfc3339(commit.time),
+        message: commit.message,
+        files: commit
+            .files
+            .iter()
+            .map(|(name, contents)| FileJson {
+                name: *name,
+                size: contents.len(),
+                sha256: format!("{:x}", Sha256::digest(contents)),
+                url: format!(
+                    "/api/v1/modules/{}/@v/{}/{}",
+                    module_path, commit.version, name
+                ),
+            })
+            .collect(),
+    }
+}
+
+// rfc3339 formats a unix time in UTC, such as '2026-10-15T00:00:00Z'.
+pub fn rfc3339(t: i64) -> String {
+    let (days, secs) = (t.div_euclid(86400), t.rem_euclid(86400));
+    // days to a date, per http://howardhinnant.github.io/date_algorithms.html#civil_from_days
+    let z = days + 719468;
+    let era = z.div_euclid(146097);
+    let doe = z.rem_euclid(146097);
+    let yoe = (doe - doe / 1460 + doe / 36524 - doe / 146096) / 365;
+    let doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+    let mp = (5 * doy + 2) / 153;
+    let day = doy - (153 * mp + 2) / 5 + 1;
+    let month = if mp < 10 { mp + 3 } else { mp - 9 };
+    let year = yoe + era * 400 + if month <= 2 { 1 } else { 0 };
+    format!(
+        "{:04}-{:02}-{:02}T{:02}:{:02}:{:02}Z",
+        year,
+        month,
+        day,
+        secs / 3600,
+        secs / 60 % 60,
+        secs % 60
+    )
+}
+
+fn not_found(what: &str) -> Response {
+    (
+        StatusCode::NOT_FOUND,
+        Json(serde_json::json!({ "error": format!("no such {}", what) })),
+    )
+        .into_response()
+}
+
+async fn families() -> impl IntoResponse {
+    Json(FAMILIES.iter().map(family_json).collect::<Vec<_>>())
+}
+
+async fn family(Path(name): Path<String>) -> Response {
+    match FAMILIES.iter().find(|f| f.name == name) {
+        Some(family) => Json(family_json(family)).into_response(),
+        None => not_found("family"),
+    }
+}
+
+async fn module(Path(path): Path<String>) -> Response {
+    match pool::pool().run(move || module_response(&path)).await {
+        Ok(res) => res,
+        Err(e) => {
+            println!("could not answer for a module: {}", e);
+            (
+                StatusCode::INTERNAL_SERVER_ERROR,
+                Json(serde_json::json!({ "error": "internal server error" })),
+            )
+                .into_response()
+        }
+    }
+}
+
+// module_response answers for the module, or one of its versions or files, at path. It renders the
+// module's files, so it runs on the pool.
+fn module_response(path: &str) -> Response {
+    let (module_path, rest) = match path.split_once("/@v/") {
+        Some((module_path, rest)) => (module_path, Some(rest)),
+        None => (path, None),
+    };
+    let module = match family::parse_module(module_path) {
+        Some(module) => module,
+        None => return not_found("module"),
+    };
+    let module_path = module.path();
+    let commits = module.commits();
+
+    let (version, file) = match rest {
+        None => {
+            return Json(ModuleJson {
+                family: module.family.name,
+                parameters: module
+                    .family
+                    .params
+                    .iter()
+                    .map(|p| p.name)
+                    .zip(module.params.iter().copied())
+                    .collect(),
+                latest: commits.last().map(|c| c.version).unwrap_or_default(),
+                versions: commits
+                    .iter()
+                    .map(|c| version_json(&module_path, c))
+                    .collect(),
+                module_path,
+            })
+            .into_response()
+        }
+        Some(rest) => match rest.split_once('/') {
+            Some((version, file)) => (version, Some(file)),
+            None => (rest, None),
+        },
+    };
+
+    let commit = match commits.iter().find(|c| c.version == version) {
+        Some(commit) => commit,
+        None => return not_found("version"),
+    };
+    let file = match file {
+        None => return Json(version_json(&module_path, commit)).into_response(),
+        Some(file) => file,
+    };
+    match commit.files.iter().find(|(name, _)| *name == file) {
+        Some((name, contents)) => Json(SourceJson {
+            module_path,
+            version: commit.version,
+            name: *name,
+            size: contents.len(),
+            sha256: format!("{:x}", Sha256::digest(contents)),
+            content: String::from_utf8_lossy(contents).into_owned(),
+        })
+        .into_response(),
+        None => not_found("file"),
+    }
+}
+
+async fn openapi() -> impl IntoResponse {
+    Json(openapi_document())
+}
+
+fn openapi_document() -> serde_json::Value {
+    let string = serde_json::json!({ "type": "string" });
+    let integer = serde_json::json!({ "type": "integer", "minimum": 0 });
+    let signed = serde_json::json!({ "type": "integer" });
+    let module_param = serde_json::json!({
+        "name": "module",
+        "in": "path",
+        "required": true,
+        "description": "A module path, such as pkg.golang.fail/tuple/3/tuple. The pkg.golang.fail/ prefix is optional. Negative parameters are spelled with an n, such as pkg.golang.fail/units/1/0/n2/units.",
+        "schema": string,
+    });
+    let version_param = serde_json::json!({
+        "name": "version",
+        "in": "path",
+        "required": true,
+        "description": "A released version, such as v1.1.0.",
+        "schema": string,
+    });
+    let json_response = |description: &str, schema: &str| {
+        serde_json::json!({
+            "description": description,
+            "content": {
+                "application/json": {
+                    "schema": { "$ref": format!("#/components/schemas/{}", schema) },
+                },
+            },
+        })
+    };
+    let not_found = json_response("Not found.", "Error");
+
+    serde_json::json!({
+        "openapi": "3.1.0",
+        "info": {
+            "title": "pkg.golang.fail",
+            "version": "1",
+            "description": "Generated go module families, and the versions, file hashes and source of their modules.",
+        },
+        "paths": {
+            "/api/v1/families": {
+                "get": {
+                    "summary": "List every module family.",
+                    "responses": {
+                        "200": {
+                            "description": "Every family.",
+                            "content": {
+                                "application/json": {
+                                    "schema": {
+                                        "type": "array",
+                                        "items": { "$ref": "#/components/schemas/Family" },
+                                    },
+                                },
+                            },
+                        },
+                    },
+                },
+            },
+            "/api/v1/families/{family}": {
+                "get": {
+                    "summary": "Describe one module family.",
+                    "parameters": [{
+                        "name": "family",
+                        "in": "path",
+                        "required": true,
+                        "schema": string,
+                    }],
+                    "responses": {
+                        "200": json_response("The family.", "Family"),
+                        "404": not_found,
+                    },
+                },
+            },
+            "/api/v1/modules/{module}": {
+                "get": {
+                    "summary": "List a module's versions, with the hash of every file.",
+                    "parameters": [module_param],
+                    "responses": {
+                        "200": json_response("The module.", "Module"),
+                        "404": not_found,
+                    },
+                },
+            },
+            "/api/v1/modules/{module}/@v/{version}": {
+                "get": {
+                    "summary": "Describe one version of a module.",
+                    "parameters": [module_param, version_param],
+                    "responses": {
+                        "200": json_response("The version.", "Version"),
+                        "404": not_found,
+                    },
+                },
+            },
+            "/api/v1/modules/{module}/@v/{version}/{file}": {
+                "get": {
+                    "summary": "Get the source of one file of a version of a module.",
+                    "parameters": [module_param, version_param, {
+                        "name": "file",
+                        "in": "path",
+                        "required": true,
+                        "description": "A file name, such as go.mod.",
+                        "schema": string,
+                    }],
+                    "responses": {
+                        "200": json_response("The file.", "Source"),
+                        "404": not_found,
+                    },
+                },
+            },
+        },
+        "components": {
+            "schemas": {
+                "Error": {
+                    "type": "object",
+                    "properties": { "error": string },
+                },
+                "Family": {
+                    "type": "object",
+                    "properties": {
+                        "name": string,
+                        "description": string,
+                        "page": { "type": "string", "description": "The family's page on this site." },
+                        "module_path": { "type": "string", "description": "The latest major version's module path, with a {name} for each parameter." },
+                        "parameters": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "properties": { "name": string, "minimum": signed, "maximum": signed },
+                            },
+                        },
+                        "versions": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "properties": { "version": string, "module_path": string },
+                            },
+                        },
+                    },
+                },
+                "Module": {
+                    "type": "object",
+                    "properties": {
+                        "module_path": string,
+                        "family": string,
+                        "parameters": { "type": "object", "additionalProperties": signed },
+                        "latest": string,
+                        "versions": {
+                            "type": "array",
+                            "items": { "$ref": "#/components/schemas/Version" },
+                        },
+                    },
+                },
+                "Version": {
+                    "type": "object",
+                    "properties": {
+                        "version": string,
+                        "time": { "type": "string", "format": "date-time" },
+                        "message": string,
+                        "files": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "properties": {
+                                    "name": string,
+                                    "size": integer,
+                                    "sha256": string,
+                                    "url": { "type": "string", "description": "Where to get the file's source." },
+                                },
+                            },
+                        },
+                    },
+                },
+                "Source": {
+                    "type": "object",
+                    "properties": {
+                        "module_path": string,
+                        "version": string,
+                        "name": string,
+                        "size": integer,
+                        "sha256": string,
+                        "content": string,
+                    },
+                },
+            },
+        },
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn formats_rfc3339() {
+        for (t, formatted) in [
+            (0, "1970-01-01T00:00:00Z"),
+            (420, "1970-01-01T00:07:00Z"),
+            (-1, "1969-12-31T23:59:59Z"),
+            // leap days, including in a century year that's a leap year, and one that isn't
+            (951782400, "2000-02-29T00:00:00Z"),
+            (4107456000, "2100-02-28T00:00:00Z"),
+            (4107542400, "2100-03-01T00:00:00Z"),
+            (1792108799, "2026-10-15T23:59:59Z"),
+            (-62135596800, "0001-01-01T00:00:00Z"),
+        ] {
+            assert_eq!(rfc3339(t), formatted, "{}", t);
+        }
+    }
+}
@@ -0,0 +1,141 @@
+// SVG badges for generated modules, for READMEs which pin them:
+//
+//   /badge/<module>.svg           the latest version, such as 'v1.2.0'
+//   /badge/<module>/template.svg  a fingerprint of the latest version's files, which only changes
+//                                 when the template generating them does
+//   /badge/<module>/clones.svg    the number of clones and fetches of the module's repo since the
+//                                 server started
+//
+// where <module> is a module path, such as 'tuple/3/tuple', or a family name followed by its
+// parameters, such as 'tuple/3', for the latest major version.
+
+use std::collections::HashMap;
+use std::sync::{Mutex, OnceLock};
+
+use axum::extract::Path;
+use axum::http::StatusCode;
+use axum::response::{IntoResponse, Response};
+use sha2::{Digest, Sha256};
+
+use crate::{family, pool};
+
+fn clones() -> &'static Mutex<HashMap<String, u64>> {
+    static CLONES: OnceLock<Mutex<HashMap<String, u64>>> = OnceLock::new();
+    CLONES.get_or_init(Default::default)
+}
+
+// count_clone counts a clone or fetch of the named repo (see init_repo), once it's been sent a pack.
+// Counts are per process and only kept in memory: they start from zero when the server does, and
+// each of several servers sharing a repos directory counts only the clones it served.
+pub fn count_clone(repo: &str) {
+    *clones()
+        .lock()
+        .unwrap()
+        .entry(repo.to_string())
+        .or_default() += 1;
+}
+
+pub async fn badge(Path(path): Path<String>) -> Response {
+    // finding the latest release renders the module's files, so do it on the pool
+    let svg = match pool::pool().run(move || render(&path)).await {
+        Ok(Some(svg)) => svg,
+        Ok(None) => return not_found(),
+        Err(e) => {
+            println!("could not render badge: {}", e);
+            return StatusCode::INTERNAL_SERVER_ERROR.into_response();
+        }
+    };
+    (
+        [
+            ("Content-Type", "image/svg+xml"),
+            // short, so clone counts stay fresh in caching image proxies
+            ("Cache-Control", "max-age=300"),
+        ],
+        svg,
+    )
+        .into_response()
+}
+
+// render renders the badge at path, such as 'tuple/3/template.svg', if there's such a module.
+fn render(path: &str) -> Option<String> {
+    let path = path.strip_suffix(".svg")?;
+    let (path, kind) = match path.rsplit_once('/') {
+        Some((module, kind @ ("template" | "clones"))) => (module, kind),
+        _ => (path, "version"),
+    };
+    let release = family::latest_release(path)?;
+
+    Some(match kind {
+        "template" => {
+            let mut hash = Sha256::new();
+            for (name, contents) in &release.files {
+                hash.update(name.as_bytes());
+                hash.update([0]);
+                hash.update((contents.len() as u64).to_be_bytes());
+                hash.update(contents);
+            }
+            let fingerprint = format!("{:x}", hash.finalize());
+            svg("template", &fingerprint[..7], "#6f42c1")
+        }
+        "clones" => {
+            let count = clones()
+                .lock()
+                .unwrap()
+                .get(&release.repo)
+                .copied()
+                .unwrap_or(0);
+            svg("clones", &count.to_string(), "#44cc11")
+        }
+        _ => svg(&release.module_path, release.version, "#007ec6"),
+    })
+}
+
+fn not_found() -> Response {
+    (
+        StatusCode::NOT_FOUND,
+        [("Content-Type", "image/svg+xml")],
+        svg("badge", "no such module", "#9f9f9f"),
+    )
+        .into_response()
+}
+
+// svg renders a flat badge, with a grey label on the left and a colored message on the right.
+fn svg(label: &str, message: &str, color: &str) -> String {
+    let (label, message) = (escape(label), escape(message));
+    let (label_width, message_width) = (text_width(&label) + 10, text_width(&message) + 10);
+    let width = label_width + message_width;
+    format!(
+        r##"<svg xmlns="http://www.w3.org/2000/svg" width="{width}" height="20" role="img" aria-label="{label}: {message}">
+<title>{label}: {message}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{width}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{label_width}" height="20" fill="#555"/><rect x="{label_width}" width="{message_width}" height="20" fill="{color}"/><rect width="{width}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{label_x}" y="15" fill="#010101" fill-opacity=".3">{label}</text><text x="{label_x}" y="14">{label}</text>
+<text x="{message_x}" y="15" fill="#010101" fill-opacity=".3">{message}</text><text x="{message_x}" y="14">{message}</text>
+</g>
+</svg>
+"##,
+        label_x = label_width / 2,
+        message_x = label_width + message_width / 2,
+    )
+}
+
+// text_width estimates the width of text in 11px Verdana, which is close enough for badges.
+fn text_width(text: &str) -> u64 {
+    text.chars()
+        .map(|c| match c {
+            'i' | 'j' | 'l' | '.' | ',' | ':' | ';' | '!' | '|' | '\'' => 4,
+            'f' | 'r' | 't' | '/' | '-' | ' ' | '(' | ')' => 5,
+            'm' | 'w' | 'M' | 'W' => 10,
+            _ => 7,
+        })
+        .sum()
+}
+
+fn escape(s: &str) -> String {
+    s.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+        .replace('"', "&quot;")
+}
@@ -0,0 +1,222 @@
+// The function decorator module template: retries, timeouts, logging and rate limiting for
+// functions of the form func(ctx, A0..Ain) (R0..Rout, error), keeping their exact signature.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791993600;
+
+pub fn module_path(inn: u64, out: u64) -> String {
+    format!("pkg.golang.fail/decorate/{}/{}", inn, out)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(inn: u64, out: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.21\n", module_path(inn, out)).into_bytes(),
+        ),
+        ("decorate.go", decorate_go(inn, out).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn list(n: u64, f: impl Fn(u64) -> String) -> String {
+    (0..n).map(f).collect::<Vec<_>>().join(", ")
+}
+
+fn decorate_go(inn: u64, out: u64) -> String {
+    let types = (0..inn)
+        .map(|i| format!("A{}", i))
+        .chain((0..out).map(|i| format!("R{}", i)))
+        .collect::<Vec<_>>()
+        .join(", ");
+    // with no arguments or results, Func isn't generic at all
+    let (decl, inst) = if types.is_empty() {
+        ("".to_string(), "".to_string())
+    } else {
+        (format!("[{} any]", types), format!("[{}]", types))
+    };
+
+    let params = (0..inn)
+        .map(|i| format!(", a{} A{}", i, i))
+        .collect::<String>();
+    let args = (0..inn).map(|i| format!(", a{}", i)).collect::<String>();
+    let results = (0..out)
+        .map(|i| format!("r{} R{}, ", i, i))
+        .collect::<String>();
+    // results to return ahead of the error, as in 'return r0, r1, err'
+    let rets = (0..out).map(|i| format!("r{}, ", i)).collect::<String>();
+    // the same, but for the results of a call made on another goroutine
+    let call_rets = (0..out).map(|i| format!("cr{}, ", i)).collect::<String>();
+    let call_vars = (0..out)
+        .map(|i| format!("\t\tvar cr{} R{}\n", i, i))
+        .collect::<String>();
+
+    let func_results = if out == 0 {
+        "error".to_string()
+    } else {
+        format!("({}, error)", list(out, |i| format!("R{}", i)))
+    };
+    let signature = format!(
+        "func(ctx context.Context{}) {}",
+        (0..inn).map(|i| format!(", A{}", i)).collect::<String>(),
+        func_results
+    );
+    let arguments = if inn == 1 { "argument" } else { "arguments" };
+    let results_word = if out == 1 { "result" } else { "results" };
+
+    format!(
+        r#"// Package decorate provides decorators for functions taking a context and {inn} {arguments}, and
+// returning {out} {results_word} and an error. Each decorator returns a function with exactly the
+// same signature as the one it decorates, so they can be stacked:
+//
+//	f = decorate.Logged(decorate.Retry(decorate.Timeout(f, time.Second), 3, nil), logger, "f")
+package decorate
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Func is the type of the functions this package decorates:
+//
+//	{signature}
+type Func{decl} func(ctx context.Context{params}) ({results}err error)
+
+// Retry returns a function which calls f until it succeeds, up to attempts times in all, and
+// returns the results of the last call. Before the i'th retry, counting from 1, it waits for
+// backoff(i), or doesn't wait if backoff is nil. If ctx is done while waiting, it gives up and
+// returns ctx's error.
+func Retry{decl}(f Func{inst}, attempts int, backoff func(retry int) time.Duration) Func{inst} {{
+	return func(ctx context.Context{params}) ({results}err error) {{
+		for retry := 1; ; retry++ {{
+			{rets}err = f(ctx{args})
+			if err == nil || retry >= attempts {{
+				return {rets}err
+			}}
+			var d time.Duration
+			if backoff != nil {{
+				d = backoff(retry)
+			}}
+			if err := sleep(ctx, d); err != nil {{
+				return zeroResults{inst}(err)
+			}}
+		}}
+	}}
+}}
+
+// Timeout returns a function which calls f with a context that's canceled after d. If f hasn't
+// returned by then, the function returns context.DeadlineExceeded without waiting for it, and f's
+// results are discarded whenever it does return.
+func Timeout{decl}(f Func{inst}, d time.Duration) Func{inst} {{
+	return func(ctx context.Context{params}) ({results}err error) {{
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+{call_vars}		var callErr error
+		done := make(chan struct{{}})
+		go func() {{
+			defer close(done)
+			{call_rets}callErr = f(ctx{args})
+		}}()
+
+		select {{
+		case <-done:
+			return {call_rets}callErr
+		case <-ctx.Done():
+			return zeroResults{inst}(ctx.Err())
+		}}
+	}}
+}}
+
+// Logged returns a function which calls f, and then logs the call's duration to logger, with name
+// as the message: at info level if it succeeded, or at error level, along with the error, if it
+// failed. The arguments and results aren't logged.
+func Logged{decl}(f Func{inst}, logger *slog.Logger, name string) Func{inst} {{
+	return func(ctx context.Context{params}) ({results}err error) {{
+		start := time.Now()
+		{rets}err = f(ctx{args})
+
+		level := slog.LevelInfo
+		attrs := []slog.Attr{{slog.Duration("duration", time.Since(start))}}
+		if err != nil {{
+			level = slog.LevelError
+			attrs = append(attrs, slog.Any("error", err))
+		}}
+		logger.LogAttrs(ctx, level, name, attrs...)
+		return {rets}err
+	}}
+}}
+
+// RateLimited returns a function which calls f at most once every interval on average, allowing
+// bursts of up to burst calls at once. Calls over the limit wait their turn, unless ctx is done
+// first, in which case they return ctx's error without calling f, and give their turn back. The
+// limit is shared by every call to the returned function, but not with other calls to RateLimited.
+func RateLimited{decl}(f Func{inst}, interval time.Duration, burst int) Func{inst} {{
+	l := &limiter{{interval: interval, burst: max(burst, 1)}}
+	return func(ctx context.Context{params}) ({results}err error) {{
+		if err := sleep(ctx, l.reserve()); err != nil {{
+			l.cancel()
+			return zeroResults{inst}(err)
+		}}
+		return f(ctx{args})
+	}}
+}}
+
+// zeroResults returns zero values along with err.
+func zeroResults{decl}(err error) ({results}_ error) {{
+	return {rets}err
+}}
+
+// sleep waits for d, or returns ctx's error if it's done first.
+func sleep(ctx context.Context, d time.Duration) error {{
+	if err := ctx.Err(); err != nil {{
+		return err
+	}}
+	if d <= 0 {{
+		return nil
+	}}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {{
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}}
+}}
+
+// limiter is a token bucket, tracked as the time at which it'll next be full (the generic cell
+// rate algorithm).
+type limiter struct {{
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	full     time.Time
+}}
+
+// reserve takes a token from the bucket, returning how long to wait until it's available.
+func (l *limiter) reserve() time.Duration {{
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.full.Before(now) {{
+		l.full = now
+	}}
+	l.full = l.full.Add(l.interval)
+	return l.full.Sub(now) - time.Duration(l.burst)*l.interval
+}}
+
+// cancel gives back a token taken by reserve, for a call which didn't go ahead.
+func (l *limiter) cancel() {{
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.full = l.full.Add(-l.interval)
+}}
+"#
+    )
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	// decorate functions taking a context and 1 argument, and returning 1 result and an error
+	decorate11 "pkg.golang.fail/decorate/1/1"
+)
+
+var flaky = 2
+
+// fetch fails the first couple of times it's called
+func fetch(ctx context.Context, url string) (int, error) {
+	if flaky > 0 {
+		flaky--
+		return 0, errors.New("connection reset")
+	}
+	return len(url), nil
+}
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// each decorator keeps fetch's exact signature, so they stack, and the result is still a
+	// func(context.Context, string) (int, error)
+	f := decorate11.Timeout(fetch, time.Second)
+	f = decorate11.Retry(f, 3, func(retry int) time.Duration {
+		return time.Duration(retry) * 10 * time.Millisecond
+	})
+	f = decorate11.Logged(f, logger, "fetch")
+	f = decorate11.RateLimited(f, 100*time.Millisecond, 1)
+
+	start := time.Now()
+	n, err := f(context.Background(), "https://pkg.golang.fail")
+	fmt.Println(n, err) // 23 <nil>, after two retries, logged as: level=INFO msg=fetch duration=30ms
+
+	// the limit allows one call every 100ms, so this one waits for its turn
+	f(context.Background(), "https://go.dev")
+	fmt.Println(time.Since(start) >= 100*time.Millisecond) // true
+}
@@ -0,0 +1,627 @@
+// The registry of module families, from which both their go-get and git routes (see
+// module_handler) and the JSON API are built.
+
+use std::collections::VecDeque;
+use std::fmt;
+use std::sync::{Arc, Mutex, OnceLock};
+
+use serde::Serialize;
+
+use crate::{
+    decorate, initial_commit, matcher, matrix, mock, parse, ring, signal, smallvec, tuple, units,
+    vector, RepoCommit,
+};
+
+// A Family is a set of generated modules which differ only in their parameters, such as arity.
+pub struct Family {
+    pub name: &'static str,
+    pub description: &'static str,
+    // the family's page on the site
+    pub page: &'static str,
+    // the path of a v0 or v1 module, with a {name} for each parameter, in order; later major
+    // versions get a '/vN' suffix
+    pub path: &'static str,
+    pub params: &'static [Param],
+    // every version released, oldest first
+    pub versions: fn() -> Vec<&'static str>,
+    // the commit of every version released, oldest first, for the given parameters, which are
+    // within the bounds of params
+    commits: fn(&[Value]) -> Vec<RepoCommit>,
+    // whether go-get and git requests for the family's modules are routed to module_handler;
+    // tuple's are routed by hand, alongside its other endpoints
+    pub routed: bool,
+}
+
+pub struct Param {
+    pub name: &'static str,
+    pub kind: Kind,
+}
+
+#[derive(Clone, Copy)]
+pub enum Kind {
+    // a number of things, such as arity, from minimum to MAX_ARITY
+    Count { minimum: u64 },
+    // an exponent, from -maximum to maximum; negative exponents are spelled with an 'n' in module
+    // paths (see units::format_exponent)
+    Exponent { maximum: i64 },
+}
+
+// A Value is a parameter's value, of its kind.
+#[derive(Clone, Copy, Debug, PartialEq, Serialize)]
+#[serde(untagged)]
+pub enum Value {
+    Count(u64),
+    Exponent(i64),
+}
+
+// MAX_ARITY bounds every count parameter, such as arity, since the size of some modules grows with
+// its square, and any module may be generated on request.
+pub const MAX_ARITY: u64 = 256;
+
+impl Kind {
+    pub fn minimum(self) -> Value {
+        match self {
+            Kind::Count { minimum } => Value::Count(minimum),
+            Kind::Exponent { maximum } => Value::Exponent(-maximum),
+        }
+    }
+
+    pub fn maximum(self) -> Value {
+        match self {
+            Kind::Count { .. } => Value::Count(MAX_ARITY),
+            Kind::Exponent { maximum } => Value::Exponent(maximum),
+        }
+    }
+
+    // parse parses a value of this kind from a module path element, accepting only its canonical
+    // spelling, since '03' is a different module path than '3'.
+    fn parse(self, s: &str) -> Option<Value> {
+        match self {
+            Kind::Count { minimum } => {
+                let n = s.parse::<u64>().ok()?;
+                (s == n.to_string() && (minimum..=MAX_ARITY).contains(&n)).then(|| Value::Count(n))
+            }
+            Kind::Exponent { maximum } => units::parse_exponent(s)
+                .filter(|e| e.abs() <= maximum)
+                .map(Value::Exponent),
+        }
+    }
+}
+
+impl fmt::Display for Value {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        match *self {
+            Value::Count(n) => write!(f, "{}", n),
+            Value::Exponent(e) => f.write_str(&units::format_exponent(e)),
+        }
+    }
+}
+
+// counts returns the values of a family's parameters, which are all counts.
+fn counts<const N: usize>(params: &[Value]) -> [u64; N] {
+    let counts = params.iter().map(|p| match *p {
+        Value::Count(n) => n,
+        Value::Exponent(_) => panic!("parameters are all counts"),
+    });
+    counts
+        .collect::<Vec<_>>()
+        .try_into()
+        .expect("parameters match their family's")
+}
+
+// exponents returns the values of a family's parameters, which are all exponents.
+fn exponents<const N: usize>(params: &[Value]) -> [i64; N] {
+    let exponents = params.iter().map(|p| match *p {
+        Value::Exponent(e) => e,
+        Value::Count(_) => panic!("parameters are all exponents"),
+    });
+    exponents
+        .collect::<Vec<_>>()
+        .try_into()
+        .expect("parameters match their family's")
+}
+
+const fn count(name: &'static str, minimum: u64) -> Param {
+    Param {
+        name,
+        kind: Kind::Count { minimum },
+    }
+}
+
+const N: &[Param] = &[count("n", 0)];
+const N_POSITIVE: &[Param] = &[count("n", 1)];
+const IN_OUT: &[Param] = &[count("in", 0), count("out", 0)];
+// the exponents of metres, kilograms and seconds in a dimension, for the units family
+const DIMENSION: &[Param] = &[
+    exponent("m", MAX_EXPONENT),
+    exponent("kg", MAX_EXPONENT),
+    exponent("s", MAX_EXPONENT),
+];
+// the exponents of two dimensions, for the units-mul and units-div families, which are bounded by
+// half as much, so that the dimension of their product or quotient, whose module they require, is
+// within DIMENSION's bounds too
+const DIMENSIONS: &[Param] = &[
+    exponent("m", MAX_EXPONENT / 2),
+    exponent("kg", MAX_EXPONENT / 2),
+    exponent("s", MAX_EXPONENT / 2),
+    exponent("by_m", MAX_EXPONENT / 2),
+    exponent("by_kg", MAX_EXPONENT / 2),
+    exponent("by_s", MAX_EXPONENT / 2),
+];
+
+const MAX_EXPONENT: i64 = i32::MAX as i64;
+
+const fn exponent(name: &'static str, maximum: i64) -> Param {
+    Param {
+        name,
+        kind: Kind::Exponent { maximum },
+    }
+}
+
+pub const FAMILIES: &[Family] = &[
+    Family {
+        name: "tuple",
+        description: "n-ary generic tuple",
+        page: "/tuple",
+        path: "pkg.golang.fail/tuple/{n}/tuple",
+        params: N,
+        versions: || tuple::RELEASES.iter().map(|r| r.version).collect(),
+        commits: |p| {
+            let [n] = counts(p);
+            crate::tuple_commits(n).collect()
+        },
+        routed: false,
+    },
+    Family {
+        name: "decorate",
+        description: "function decorators",
+        page: "/decorate",
+        path: "pkg.golang.fail/decorate/{in}/{out}",
+        params: IN_OUT,
+        versions: || vec![decorate::VERSION],
+        commits: |p| {
+            let [input, output] = counts(p);
+            vec![initial_commit(
+                decorate::VERSION,
+                decorate::TIME,
+                decorate::files(input, output),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "match",
+        description: "typed regexp matches",
+        page: "/match",
+        path: "pkg.golang.fail/match/{n}/match",
+        params: N,
+        versions: || vec![matcher::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(
+                matcher::VERSION,
+                matcher::TIME,
+                matcher::files(n),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "matrix",
+        description: "fixed-size matrix",
+        page: "/matrix",
+        path: "pkg.golang.fail/matrix/{r}/{c}",
+        params: &[count("r", 1), count("c", 1)],
+        versions: || vec![matrix::VERSION],
+        commits: |p| {
+            let [r, c] = counts(p);
+            vec![initial_commit(
+                matrix::VERSION,
+                matrix::TIME,
+                matrix::files(r, c),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "mock",
+        description: "function mocks and spies",
+        page: "/mock",
+        path: "pkg.golang.fail/mock/{in}/{out}",
+        params: IN_OUT,
+        versions: || vec![mock::VERSION],
+        commits: |p| {
+            let [input, output] = counts(p);
+            vec![initial_commit(
+                mock::VERSION,
+                mock::TIME,
+                mock::files(input, output),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "parse",
+        description: "parser combinators",
+        page: "/parse",
+        path: "pkg.golang.fail/parse/{n}/parse",
+        params: N,
+        versions: || vec![parse::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(parse::VERSION, parse::TIME, parse::files(n))]
+        },
+        routed: true,
+    },
+    Family {
+        name: "parser",
+        description: "parser combinators, for any number of parsers",
+        page: "/parse",
+        path: "pkg.golang.fail/parse/parser",
+        params: &[],
+        versions: || vec![parse::PARSER_VERSION],
+        commits: |_| {
+            vec![initial_commit(
+                parse::PARSER_VERSION,
+                parse::PARSER_TIME,
+                parse::parser_files(),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "ring",
+        description: "fixed-capacity ring buffer",
+        page: "/ring",
+        path: "pkg.golang.fail/ring/{n}",
+        params: N_POSITIVE,
+        versions: || vec![ring::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(ring::VERSION, ring::TIME, ring::files(n))]
+        },
+        routed: true,
+    },
+    Family {
+        name: "signal",
+        description: "n-argument signal",
+        page: "/signal",
+        path: "pkg.golang.fail/signal/{n}/signal",
+        params: N,
+        versions: || vec![signal::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(
+                signal::VERSION,
+                signal::TIME,
+                signal::files(n),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "smallvec",
+        description: "small vector",
+        page: "/smallvec",
+        path: "pkg.golang.fail/smallvec/{n}",
+        params: N_POSITIVE,
+        versions: || vec![smallvec::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(
+                smallvec::VERSION,
+                smallvec::TIME,
+                smallvec::files(n),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "units",
+        description: "dimensional quantities",
+        page: "/units",
+        path: "pkg.golang.fail/units/{m}/{kg}/{s}/units",
+        params: DIMENSION,
+        versions: || vec![units::VERSION],
+        commits: |p| {
+            let [m, kg, s] = exponents(p);
+            vec![initial_commit(
+                units::VERSION,
+                units::TIME,
+                units::files([m, kg, s]),
+            )]
+        },
+        routed: true,
+    },
+    Family {
+        name: "units-mul",
+        description: "products of dimensional quantities",
+        page: "/units",
+        path: "pkg.golang.fail/units/{m}/{kg}/{s}/mul/{by_m}/{by_kg}/{by_s}/mul",
+        params: DIMENSIONS,
+        versions: || vec![units::VERSION],
+        commits: |p| units_op_commits(units::Op::Mul, p),
+        routed: true,
+    },
+    Family {
+        name: "units-div",
+        description: "quotients of dimensional quantities",
+        page: "/units",
+        path: "pkg.golang.fail/units/{m}/{kg}/{s}/div/{by_m}/{by_kg}/{by_s}/div",
+        params: DIMENSIONS,
+        versions: || vec![units::VERSION],
+        commits: |p| units_op_commits(units::Op::Div, p),
+        routed: true,
+    },
+    Family {
+        name: "vector",
+        description: "fixed-size vector",
+        page: "/vector",
+        path: "pkg.golang.fail/vector/{n}",
+        params: N_POSITIVE,
+        versions: || vec![vector::VERSION],
+        commits: |p| {
+            let [n] = counts(p);
+            vec![initial_commit(
+                vector::VERSION,
+                vector::TIME,
+                vector::files(n),
+            )]
+        },
+        routed: true,
+    },
+];
+
+fn units_op_commits(op: units::Op, p: &[Value]) -> Vec<RepoCommit> {
+    let [m, kg, s, by_m, by_kg, by_s] = exponents(p);
+    vec![initial_commit(
+        units::VERSION,
+        units::TIME,
+        units::op_files(op, [m, kg, s], [by_m, by_kg, by_s]),
+    )]
+}
+
+// major returns the major version of a version such as 'v2.1.0', where v0 counts as v1, since they
+// share a module path.
+pub fn major(version: &str) -> u64 {
+    version[1..]
+        .split('.')
+        .next()
+        .and_then(|m| m.parse::<u64>().ok())
+        .expect("versions are of the form vX.Y.Z")
+        .max(1)
+}
+
+// module_path_suffix is the suffix semantic import versioning gives a major version's module path.
+pub fn module_path_suffix(major: u64) -> String {
+    if major < 2 {
+        "".into()
+    } else {
+        format!("/v{}", major)
+    }
+}
+
+impl Family {
+    pub fn majors(&self) -> Vec<u64> {
+        let mut majors = (self.versions)().into_iter().map(major).collect::<Vec<_>>();
+        majors.dedup();
+        majors
+    }
+
+    // parse parses the path of one of the family's modules, without the leading 'pkg.golang.fail/'.
+    fn parse(&'static self, path: &str) -> Option<Module> {
+        let template = self.path.strip_prefix("pkg.golang.fail/").unwrap();
+        let template = template.split('/').collect::<Vec<_>>();
+        let mut segments = path.split('/').collect::<Vec<_>>();
+
+        let mut major = 1;
+        if segments.len() == template.len() + 1 {
+            let suffix = segments.pop().unwrap();
+            major = self
+                .majors()
+                .into_iter()
+                .find(|&m| m >= 2 && suffix == format!("v{}", m))?;
+        }
+        if segments.len() != template.len() || !self.majors().contains(&major) {
+            return None;
+        }
+
+        let mut params = Vec::new();
+        for (t, s) in template.iter().zip(segments) {
+            if t.starts_with('{') {
+                params.push(self.params[params.len()].kind.parse(s)?);
+            } else if *t != s {
+                return None;
+            }
+        }
+        Some(Module {
+            family: self,
+            params,
+            major,
+        })
+    }
+}
+
+// A Module is one major version of one member of a family.
+pub struct Module {
+    pub family: &'static Family,
+    pub params: Vec<Value>,
+    pub major: u64,
+}
+
+impl Module {
+    pub fn path(&self) -> String {
+        format!(
+            "pkg.golang.fail/{}{}",
+            self.repo(),
+            module_path_suffix(self.major)
+        )
+    }
+
+    // repo is the name of the repo every major version of the module is served from, as given to
+    // serve_module.
+    pub fn repo(&self) -> String {
+        let mut path = self.family.path["pkg.golang.fail/".len()..].to_string();
+        for (param, value) in self.family.params.iter().zip(&self.params) {
+            path = path.replace(&format!("{{{}}}", param.name), &value.to_string());
+        }
+        path
+    }
+
+    // commits returns the commit of every release of the module, oldest first. Generating them is
+    // slow for large modules, and they never change, so recently generated modules' are cached.
+    pub fn commits(&self) -> Arc<Vec<RepoCommit>> {
+        let path = self.path();
+        let mut cache = commits_cache().lock().unwrap();
+        if let Some((_, commits)) = cache.iter().find(|(p, _)| *p == path) {
+            return commits.clone();
+        }
+        drop(cache);
+
+        let commits = Arc::new(
+            self.repo_commits()
+                .into_iter()
+                .filter(|c| major(c.version) == self.major)
+                .collect::<Vec<_>>(),
+        );
+        cache = commits_cache().lock().unwrap();
+        cache.push_back((path, commits.clone()));
+        if cache.len() > CACHED_MODULES {
+            cache.pop_front();
+        }
+        commits
+    }
+
+    // repo_commits returns the commit of every release in the module's repo, of every major
+    // version, oldest first, for write_repo.
+    pub fn repo_commits(&self) -> Vec<RepoCommit> {
+        (self.family.commits)(&self.params)
+    }
+
+    // repo_version is the latest version in the module's repo, as given to serve_module.
+    pub fn repo_version(&self) -> &'static str {
+        (self.family.versions)()
+            .last()
+            .copied()
+            .expect("families have released at least once")
+    }
+}
+
+const CACHED_MODULES: usize = 64;
+
+// commits_cache holds the commits of the modules most recently generated, oldest first.
+fn commits_cache() -> &'static Mutex<VecDeque<(String, Arc<Vec<RepoCommit>>)>> {
+    static CACHE: OnceLock<Mutex<VecDeque<(String, Arc<Vec<RepoCommit>>)>>> = OnceLock::new();
+    CACHE.get_or_init(Default::default)
+}
+
+// parse_module parses a module path, such as 'tuple/3/tuple/v2', where the 'pkg.golang.fail/' is
+// optional.
+pub fn parse_module(path: &str) -> Option<Module> {
+    let path = path.trim_matches('/');
+    let path = path.strip_prefix("pkg.golang.fail/").unwrap_or(path);
+    FAMILIES.iter().find_map(|f| f.parse(path))
+}
+
+// parse_shorthand parses a family's name followed by its parameters, such as 'tuple/3', as the
+// latest major version of that member of the family.
+fn parse_shorthand(path: &str) -> Option<Module> {
+    let (name, values) = path.trim_matches('/').split_once('/')?;
+    let family = FAMILIES.iter().find(|f| f.name == name)?;
+    let mut values = values.split('/');
+    let mut path = family.path["pkg.golang.fail/".len()..].to_string();
+    for param in family.params {
+        path = path.replace(&format!("{{{}}}", param.name), values.next()?);
+    }
+    if values.next().is_some() {
+        return None;
+    }
+    let major = *family.majors().last()?;
+    // parse checks the values, which may be anything so far
+    family.parse(&(path + &module_path_suffix(major)))
+}
+
+// A Release is the latest release of a module.
+pub struct Release {
+    // without the 'pkg.golang.fail/' prefix
+    pub module_path: String,
+    // see Module.repo
+    pub repo: String,
+    pub version: &'static str,
+    pub files: Vec<(&'static str, Vec<u8>)>,
+}
+
+// latest_release returns the latest release of the module at path, which may also be given as
+// shorthand, such as 'tuple/3' for 'tuple/3/tuple'.
+pub fn latest_release(path: &str) -> Option<Release> {
+    let module = parse_module(path).or_else(|| parse_shorthand(path))?;
+    let commits = module.commits();
+    let commit = commits.last()?;
+    Some(Release {
+        module_path: module.path()["pkg.golang.fail/".len()..].to_string(),
+        repo: module.repo(),
+        version: commit.version,
+        files: commit.files.clone(),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    // requires returns the paths of the modules the module at path requires.
+    fn requires(path: &str) -> Vec<String> {
+        let module = parse_module(path).unwrap();
+        let commits = module.commits();
+        let (_, go_mod) = commits[0]
+            .files
+            .iter()
+            .find(|(name, _)| *name == "go.mod")
+            .unwrap();
+        String::from_utf8_lossy(go_mod)
+            .lines()
+            .filter_map(|l| l.strip_prefix('\t')?.split_once(' '))
+            .map(|(path, _)| path.to_string())
+            .collect()
+    }
+
+    #[test]
+    fn bounds_exponents() {
+        for path in [
+            "units/2147483647/0/0/units",
+            "units/n2147483647/0/n2/units",
+            "units/1073741823/0/0/mul/1073741823/0/0/mul",
+            "units/n1073741823/0/0/div/1073741823/0/0/div",
+        ] {
+            assert!(parse_module(path).is_some(), "{}", path);
+        }
+        for path in [
+            "units/2147483648/0/0/units",
+            "units/n2147483648/0/0/units",
+            "units/1073741824/0/0/mul/0/0/0/mul",
+            "units/0/0/0/div/0/0/n1073741824/div",
+            "units/2147483647/0/0/mul/1/0/0/mul",
+        ] {
+            assert!(parse_module(path).is_none(), "{}", path);
+        }
+    }
+
+    #[test]
+    fn units_ops_require_served_modules() {
+        for path in [
+            "units/1073741823/0/0/mul/1073741823/0/0/mul",
+            "units/n1073741823/0/0/mul/0/n1073741823/0/mul",
+            "units/n1073741823/0/0/div/1073741823/0/0/div",
+            "units/0/1073741823/0/div/0/n1073741823/0/div",
+        ] {
+            for required in requires(path) {
+                assert!(
+                    parse_module(&required).is_some(),
+                    "{} requires {}",
+                    path,
+                    required
+                );
+            }
+        }
+    }
+}
@@ -0,0 +1,340 @@
+// A small subset of go's type syntax, enough to describe how tuple elements encode to JSON with
+// encoding/json.
+
+use serde_json::{json, Value};
+
+// MAX_ELEMS is the most elements a tuple may have for its encoding to be described, counting each
+// element of arrays within them, so that describing one stays cheap.
+pub const MAX_ELEMS: u64 = 1024;
+
+// MAX_DEPTH is how deeply types may nest, so that parsing them can't overflow the stack.
+const MAX_DEPTH: usize = 32;
+
+#[derive(Debug, PartialEq)]
+pub enum GoType {
+    Bool,
+    String,
+    Int,
+    Float,
+    // []byte, which encoding/json encodes as a base64 string
+    Bytes,
+    // time.Time, encoded as an RFC 3339 string
+    Time,
+    // any, interface{}, json.RawMessage, and any named type we don't know about
+    Any,
+    Pointer(Box<GoType>),
+    Slice(Box<GoType>),
+    Array(u64, Box<GoType>),
+    Map(Box<GoType>),
+}
+
+pub fn parse(s: &str) -> Result<GoType, anyhow::Error> {
+    let t = parse_nested(s, 0)?;
+    if t.size() > MAX_ELEMS {
+        anyhow::bail!("{:?} has more than {} elements", s.trim(), MAX_ELEMS);
+    }
+    Ok(t)
+}
+
+// parse_nested parses a type nested within depth others.
+fn parse_nested(s: &str, depth: usize) -> Result<GoType, anyhow::Error> {
+    let s = s.trim();
+    if depth > MAX_DEPTH {
+        anyhow::bail!("types nested more than {} deep aren't supported", MAX_DEPTH);
+    }
+    let parse = |s: &str| parse_nested(s, depth + 1);
+    if let Some(elem) = s.strip_prefix('*') {
+        return Ok(GoType::Pointer(Box::new(parse(elem)?)));
+    }
+    if let Some(elem) = s.strip_prefix("[]") {
+        if elem == "byte" || elem == "uint8" {
+            return Ok(GoType::Bytes);
+        }
+        return Ok(GoType::Slice(Box::new(parse(elem)?)));
+    }
+    if let Some(rest) = s.strip_prefix('[') {
+        let (len, elem) = rest
+            .split_once(']')
+            .ok_or_else(|| anyhow::anyhow!("unterminated array length in {:?}", s))?;
+        let len = len
+            .trim()
+            .parse()
+            .map_err(|_| anyhow::anyhow!("invalid array length in {:?}", s))?;
+        return Ok(GoType::Array(len, Box::new(parse(elem)?)));
+    }
+    if let Some(rest) = s.strip_prefix("map[") {
+        let (key, elem) = rest
+            .split_once(']')
+            .ok_or_else(|| anyhow::anyhow!("unterminated map key in {:?}", s))?;
+        return match parse(key)? {
+            // encoding/json encodes string and integer keys as object keys
+            GoType::String | GoType::Int => Ok(GoType::Map(Box::new(parse(elem)?))),
+            _ => anyhow::bail!("unsupported map key type in {:?}", s),
+        };
+    }
+
+    Ok(match s {
+        "bool" => GoType::Bool,
+        "string" => GoType::String,
+        "int" | "int8" | "int16" | "int32" | "int64" | "uint" | "uint8" | "uint16" | "uint32"
+        | "uint64" | "uintptr" | "byte" | "rune" => GoType::Int,
+        "float32" | "float64" => GoType::Float,
+        "time.Time" => GoType::Time,
+        "any" | "interface{}" => GoType::Any,
+        "" => anyhow::bail!("empty type"),
+        _ if is_named_type(s) => GoType::Any,
+        _ => anyhow::bail!("unsupported type {:?}", s),
+    })
+}
+
+// is_named_type is whether s is a type name, optionally qualified by its package, such as 'Point'
+// or 'netip.Addr'.
+fn is_named_type(s: &str) -> bool {
+    match s.split_once('.') {
+        Some((pkg, name)) => is_identifier(pkg) && is_identifier(name),
+        None => is_identifier(s),
+    }
+}
+
+// is_identifier is whether s is a go identifier: a letter or underscore, followed by letters,
+// digits and underscores.
+fn is_identifier(s: &str) -> bool {
+    let mut chars = s.chars();
+    matches!(chars.next(), Some(c) if c.is_alphabetic() || c == '_')
+        && chars.all(|c| c.is_alphanumeric() || c == '_')
+}
+
+// parse_list parses a comma separated list of types, as given in a 'types' query parameter, of no
+// more than MAX_ELEMS elements in all.
+pub fn parse_list(s: &str) -> Result<Vec<GoType>, anyhow::Error> {
+    if s.trim().is_empty() {
+        return Ok(vec![]);
+    }
+    let mut size = 0u64;
+    let mut types = Vec::new();
+    for s in s.split(',') {
+        let t = parse(s)?;
+        size = size.saturating_add(t.size());
+        if size > MAX_ELEMS {
+            anyhow::bail!(
+                "tuples of more than {} elements aren't supported",
+                MAX_ELEMS
+            );
+        }
+        types.push(t);
+    }
+    Ok(types)
+}
+
+impl GoType {
+    // size is how many types describing this type describes, counting each element of arrays, but
+    // saturating rather than overflowing.
+    fn size(&self) -> u64 {
+        match self {
+            GoType::Pointer(elem) | GoType::Slice(elem) | GoType::Map(elem) => elem.size(),
+            // an empty array's element type is still described once
+            GoType::Array(len, elem) => (*len).max(1).saturating_mul(elem.size()),
+            _ => 1,
+        }
+    }
+
+    // json_schema returns a JSON Schema describing the encoding/json encoding of this type.
+    pub fn json_schema(&self) -> Value {
+        match self {
+            GoType::Bool => json!({"type": "boolean"}),
+            GoType::String => json!({"type": "string"}),
+            GoType::Int => json!({"type": "integer"}),
+            GoType::Float => json!({"type": "number"}),
+            GoType::Bytes => json!({"type": ["string", "null"], "contentEncoding": "base64"}),
+            GoType::Time => json!({"type": "string", "format": "date-time"}),
+            GoType::Any => json!({}),
+            GoType::Pointer(elem) => json!({"anyOf": [elem.json_schema(), {"type": "null"}]}),
+            GoType::Slice(elem) => json!({"type": ["array", "null"], "items": elem.json_schema()}),
+            GoType::Array(len, elem) => json!({
+                "type": "array",
+                "items": elem.json_schema(),
+                "minItems": len,
+                "maxItems": len,
+            }),
+            GoType::Map(elem) => json!({
+                "type": ["object", "null"],
+                "additionalProperties": elem.json_schema(),
+            }),
+        }
+    }
+
+    // nullable is whether encoding/json may encode this type as null.
+    fn nullable(&self) -> bool {
+        matches!(
+            self,
+            GoType::Bytes | GoType::Any | GoType::Pointer(_) | GoType::Slice(_) | GoType::Map(_)
+        )
+    }
+
+    // typescript returns the TypeScript type of this type's encoding/json encoding, once parsed
+    // with JSON.parse.
+    pub fn typescript(&self) -> String {
+        match self {
+            GoType::Bool => "boolean".into(),
+            GoType::String | GoType::Time => "string".into(),
+            GoType::Int | GoType::Float => "number".into(),
+            GoType::Bytes => "string | null".into(),
+            GoType::Any => "unknown".into(),
+            GoType::Pointer(elem) if elem.nullable() => elem.typescript(),
+            GoType::Pointer(elem) => format!("{} | null", elem.typescript()),
+            GoType::Slice(elem) => {
+                let elem = elem.typescript();
+                if elem.contains(' ') {
+                    format!("({})[] | null", elem)
+                } else {
+                    format!("{}[] | null", elem)
+                }
+            }
+            GoType::Array(len, elem) => typescript_tuple(&vec![elem.typescript(); *len as usize]),
+            GoType::Map(elem) => format!("Record<string, {}> | null", elem.typescript()),
+        }
+    }
+
+    // python returns the Python annotation of this type's encoding/json encoding, once parsed with
+    // json.loads.
+    pub fn python(&self) -> String {
+        match self {
+            GoType::Bool => "bool".into(),
+            GoType::String | GoType::Time => "str".into(),
+            GoType::Int => "int".into(),
+            GoType::Float => "float".into(),
+            GoType::Bytes => "str | None".into(),
+            GoType::Any => "Any".into(),
+            GoType::Pointer(elem) if elem.nullable() => elem.python(),
+            GoType::Pointer(elem) => format!("{} | None", elem.python()),
+            GoType::Slice(elem) => format!("list[{}] | None", elem.python()),
+            GoType::Array(len, elem) => python_tuple(&vec![elem.python(); *len as usize]),
+            GoType::Map(elem) => format!("dict[str, {}] | None", elem.python()),
+        }
+    }
+}
+
+fn typescript_tuple(elems: &[String]) -> String {
+    format!("[{}]", elems.join(", "))
+}
+
+fn python_tuple(elems: &[String]) -> String {
+    if elems.is_empty() {
+        return "tuple[()]".into();
+    }
+    format!("tuple[{}]", elems.join(", "))
+}
+
+// tuple_typescript returns a TypeScript module declaring the type of a tuple with the given element
+// types as encoding/json encodes it: an object with a field per element, named T0, T1 and so on,
+// since Tuple has no MarshalJSON method.
+pub fn tuple_typescript(types: &[GoType]) -> String {
+    if types.is_empty() {
+        return "export type Tuple = Record<string, never>;\n".into();
+    }
+    let fields = types
+        .iter()
+        .enumerate()
+        .map(|(i, t)| format!("T{}: {}", i, t.typescript()))
+        .collect::<Vec<_>>();
+    format!("export type Tuple = {{ {} }};\n", fields.join("; "))
+}
+
+// tuple_python returns a Python module declaring an annotation for a tuple with the given element
+// types as encoding/json encodes it, like tuple_typescript, once parsed with json.loads.
+pub fn tuple_python(types: &[GoType]) -> String {
+    let fields = types
+        .iter()
+        .enumerate()
+        .map(|(i, t)| format!("\"T{}\": {}", i, t.python()))
+        .collect::<Vec<_>>();
+    let tuple = format!("TypedDict(\"Tuple\", {{{}}})", fields.join(", "));
+    // no other annotation we render contains 'Any'
+    let imports = if tuple.contains("Any") {
+        "Any, TypedDict"
+    } else {
+        "TypedDict"
+    };
+    format!("from typing import {}\n\nTuple = {}\n", imports, tuple)
+}
+
+// tuple_json_schema returns the schema the generated tuple module's JSONSchema function returns
+// for the given element schemas, plus a '$schema' naming its draft, since it's served as a document
+// of its own.
+pub fn tuple_json_schema(elems: Vec<Value>) -> Value {
+    let n = elems.len();
+    json!({
+        "$schema": "https://json-schema.org/draft/2020-12/schema",
+        "type": "array",
+        "prefixItems": elems,
+        "items": false,
+        "minItems": n,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_nested_types() {
+        assert_eq!(
+            parse("map[string]*[2][]byte").unwrap(),
+            GoType::Map(Box::new(GoType::Pointer(Box::new(GoType::Array(
+                2,
+                Box::new(GoType::Bytes)
+            )))))
+        );
+        assert_eq!(parse("netip.Addr").unwrap(), GoType::Any);
+        assert!(parse("[2]").is_err());
+        assert!(parse("map[bool]int").is_err());
+        assert!(parse("1x").is_err());
+    }
+
+    #[test]
+    fn caps_array_sizes() {
+        assert!(parse("[32][32]int").is_ok());
+        assert!(parse("[1024]int").is_ok());
+        assert!(parse("[1025]int").is_err());
+        assert!(parse("[33][32]int").is_err());
+        assert!(parse("[18446744073709551615][18446744073709551615]int").is_err());
+        // the element type of an empty array is still described
+        assert!(parse("[0][1025]int").is_err());
+        assert!(parse("[]map[string][1025]int").is_err());
+    }
+
+    #[test]
+    fn caps_lists() {
+        assert_eq!(parse_list("[512]int,[512]int").unwrap().len(), 2);
+        assert!(parse_list("[512]int,[512]int,int").is_err());
+        assert!(parse_list(&vec!["int"; 1025].join(",")).is_err());
+    }
+
+    #[test]
+    fn stubs_describe_encoding_json_objects() {
+        let types = parse_list("string,[2]int,*any").unwrap();
+        assert_eq!(
+            tuple_typescript(&types),
+            "export type Tuple = { T0: string; T1: [number, number]; T2: unknown };\n"
+        );
+        assert_eq!(
+            tuple_python(&types),
+            "from typing import Any, TypedDict\n\nTuple = TypedDict(\"Tuple\", {\"T0\": str, \"T1\": tuple[int, int], \"T2\": Any})\n"
+        );
+        assert_eq!(
+            tuple_typescript(&[]),
+            "export type Tuple = Record<string, never>;\n"
+        );
+        assert_eq!(
+            tuple_python(&[]),
+            "from typing import TypedDict\n\nTuple = TypedDict(\"Tuple\", {})\n"
+        );
+    }
+
+    #[test]
+    fn caps_nesting() {
+        assert!(parse(&format!("{}int", "*".repeat(32))).is_ok());
+        assert!(parse(&format!("{}int", "*".repeat(33))).is_err());
+        assert!(parse(&format!("{}int", "[]".repeat(100_000))).is_err());
+    }
+}
@@ -0,0 +1,238 @@
+// Server-side rendering of go code for the docs pages: syntax highlighting with an anchor for each
+// line, and one-line snippets with a button to copy them.
+
+use maud::{html, Markup, PreEscaped};
+
+const KEYWORDS: &[&str] = &[
+    "break",
+    "case",
+    "chan",
+    "const",
+    "continue",
+    "default",
+    "defer",
+    "else",
+    "fallthrough",
+    "for",
+    "func",
+    "go",
+    "goto",
+    "if",
+    "import",
+    "interface",
+    "map",
+    "package",
+    "range",
+    "return",
+    "select",
+    "struct",
+    "switch",
+    "type",
+    "var",
+];
+
+// the predeclared identifiers, which are highlighted unless shadowed, since no one shadows them
+const BUILTINS: &[&str] = &[
+    "any",
+    "append",
+    "bool",
+    "byte",
+    "cap",
+    "clear",
+    "close",
+    "comparable",
+    "complex",
+    "complex128",
+    "complex64",
+    "copy",
+    "delete",
+    "error",
+    "false",
+    "float32",
+    "float64",
+    "imag",
+    "int",
+    "int16",
+    "int32",
+    "int64",
+    "int8",
+    "iota",
+    "len",
+    "make",
+    "max",
+    "min",
+    "new",
+    "nil",
+    "panic",
+    "print",
+    "println",
+    "real",
+    "recover",
+    "rune",
+    "string",
+    "true",
+    "uint",
+    "uint16",
+    "uint32",
+    "uint64",
+    "uint8",
+    "uintptr",
+];
+
+const STYLE: &str = "
+pre.go { background: #f8f8f8; padding: 0.5em 0; }
+pre.go .line:target { background: #fff3b0; }
+pre.go .lineno { display: inline-block; width: 3em; padding-right: 1em; text-align: right; color: #999; text-decoration: none; user-select: none; }
+pre.go .kw { color: #007020; font-weight: bold; }
+pre.go .builtin { color: #06287e; }
+pre.go .str { color: #4070a0; }
+pre.go .num { color: #40a070; }
+pre.go .com { color: #60a0b0; font-style: italic; }
+.snippet { display: flex; gap: 0.5em; align-items: center; margin: 0.25em 0; }
+.snippet code { background: #f8f8f8; padding: 0.25em 0.5em; }
+";
+
+const SCRIPT: &str = "
+function copySnippet(button) {
+  navigator.clipboard.writeText(button.previousElementSibling.textContent).then(function () {
+    button.textContent = 'copied';
+    setTimeout(function () { button.textContent = 'copy'; }, 1500);
+  });
+}
+";
+
+// head returns the styles and script used by go and snippet, for each page using them.
+pub fn head() -> Markup {
+    html! {
+        style { (PreEscaped(STYLE)) }
+        script { (PreEscaped(SCRIPT)) }
+    }
+}
+
+// go renders go source code, highlighted, with each line anchored as '#L<number>'.
+pub fn go(src: &str) -> Markup {
+    let lines = lines(tokenize(src.trim_end_matches('\n')));
+    html! {
+        pre.go {
+            @for (i, line) in lines.iter().enumerate() {
+                span.line id=(format!("L{}", i + 1)) {
+                    a.lineno href=(format!("#L{}", i + 1)) { (i + 1) }
+                    @for (class, text) in line {
+                        @if let Some(class) = class {
+                            span class=(class) { (text) }
+                        } @else {
+                            (text)
+                        }
+                    }
+                }
+                "\n"
+            }
+        }
+    }
+}
+
+// snippet renders a line of text, such as a go get command, with a button copying it.
+pub fn snippet(text: &str) -> Markup {
+    html! {
+        div.snippet {
+            code { (text) }
+            button type="button" onclick="copySnippet(this)" { "copy" }
+        }
+    }
+}
+
+type Token<'a> = (Option<&'static str>, &'a str);
+
+// tokenize splits go source code into tokens, classified for highlighting. It's only as precise as
+// highlighting needs: everything it doesn't classify, such as operators and identifiers, is left
+// unclassified, and invalid code is tokenized somehow rather than rejected.
+fn tokenize(src: &str) -> Vec<Token<'_>> {
+    let mut tokens = Vec::new();
+    let bytes = src.as_bytes();
+    let mut i = 0;
+    // the start of the unclassified text before the current token
+    let mut plain = 0;
+    while i < bytes.len() {
+        let start = i;
+        let class = match bytes[i] {
+            b'/' if bytes.get(i + 1) == Some(&b'/') => {
+                i = src[i..].find('\n').map_or(src.len(), |end| i + end);
+                "com"
+            }
+            b'/' if bytes.get(i + 1) == Some(&b'*') => {
+                i = src[i + 2..]
+                    .find("*/")
+                    .map_or(src.len(), |end| i + 2 + end + 2);
+                "com"
+            }
+            b'`' => {
+                i = src[i + 1..]
+                    .find('`')
+                    .map_or(src.len(), |end| i + 1 + end + 1);
+                "str"
+            }
+            quote @ (b'"' | b'\'') => {
+                i += 1;
+                while i < bytes.len() && bytes[i] != quote && bytes[i] != b'\n' {
+                    i += if bytes[i] == b'\\' { 2 } else { 1 };
+                }
+                i = (i + 1).min(bytes.len());
+                "str"
+            }
+            b'0'..=b'9' => {
+                while i < bytes.len()
+                    && (bytes[i].is_ascii_alphanumeric() || b"._".contains(&bytes[i]))
+                {
+                    i += 1;
+                }
+                "num"
+            }
+            c if c.is_ascii_alphabetic() || c == b'_' => {
+                while i < bytes.len() && (bytes[i].is_ascii_alphanumeric() || bytes[i] == b'_') {
+                    i += 1;
+                }
+                let word = &src[start..i];
+                // fields and methods, such as t.len, aren't the predeclared identifiers
+                let selected = src[..start].ends_with('.');
+                if KEYWORDS.contains(&word) {
+                    "kw"
+                } else if BUILTINS.contains(&word) && !selected {
+                    "builtin"
+                } else {
+                    continue;
+                }
+            }
+            _ => {
+                // skip whole characters, so slices stay on character boundaries
+                i += src[i..].chars().next().map_or(1, char::len_utf8);
+                continue;
+            }
+        };
+        if plain < start {
+            tokens.push((None, &src[plain..start]));
+        }
+        tokens.push((Some(class), &src[start..i]));
+        plain = i;
+    }
+    if plain < src.len() {
+        tokens.push((None, &src[plain..]));
+    }
+    tokens
+}
+
+// lines splits tokens into lines, splitting tokens which span lines, such as raw strings, into a
+// token on each.
+fn lines(tokens: Vec<Token<'_>>) -> Vec<Vec<Token<'_>>> {
+    let mut lines = vec![Vec::new()];
+    for (class, text) in tokens {
+        for (i, part) in text.split('\n').enumerate() {
+            if i > 0 {
+                lines.push(Vec::new());
+            }
+            if !part.is_empty() {
+                lines.last_mut().unwrap().push((class, part));
+            }
+        }
+    }
+    lines
+}
@@ -0,0 +1,449 @@
+// The 'loadtest' subcommand, which starts a server locally and measures how it copes with many
+// concurrent clones:
+//
+//   pkg-golang-fail loadtest [--arities 0-8] [--sessions 200] [--concurrency 32] [--servers 1]
+//
+// Each session is what 'go get' does over git's smart HTTP protocol: a GET of '/info/refs',
+// followed by a POST to '/git-upload-pack' wanting the main branch. Sessions are spread over the
+// arities, first against a fresh repos directory, where the first sessions for each arity have to
+// wait for it to be generated ("cold"), and then again once every repo exists ("warm").
+//
+// For each phase, it reports p50 and p99 latency per request type, along with the server's peak
+// and total number of subprocesses (git-upload-pack) and its peak memory use, sampled from /proc.
+// The server is started from this same binary, in a temporary directory, without UPSTREAM set.
+//
+// With more than one server, each is started in the same directory, so they share one repos
+// directory, and sessions are spread over them. The cold phase then also checks that each repo was
+// generated by just one of them, which fails otherwise, as a failed request would.
+
+use std::io::{Read, Write};
+use std::net::{SocketAddr, TcpStream};
+use std::sync::atomic::{AtomicBool, AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+pub fn main(args: &[String]) -> i32 {
+    let mut arities = 0..=8;
+    let mut sessions = 200;
+    let mut concurrency = 32;
+    let mut servers = 1;
+
+    let mut args = args.iter();
+    while let Some(arg) = args.next() {
+        let value = args.next().map(String::as_str);
+        let ok = match arg.as_str() {
+            "--arities" => value.and_then(parse_range).map(|r| arities = r).is_some(),
+            "--sessions" => value
+                .and_then(|v| v.parse().ok())
+                .map(|v| sessions = v)
+                .is_some(),
+            "--concurrency" => value
+                .and_then(|v| v.parse().ok())
+                .filter(|&v| v > 0)
+                .map(|v| concurrency = v)
+                .is_some(),
+            "--servers" => value
+                .and_then(|v| v.parse().ok())
+                .filter(|&v| v > 0)
+                .map(|v| servers = v)
+                .is_some(),
+            _ => false,
+        };
+        if !ok {
+            eprintln!(
+                "usage: pkg-golang-fail loadtest [--arities 0-8] [--sessions 200] [--concurrency 32] [--servers 1]"
+            );
+            return 2;
+        }
+    }
+
+    match run(arities, sessions, concurrency, servers) {
+        Ok(failed) if failed > 0 => {
+            println!("{} requests failed", failed);
+            1
+        }
+        Ok(_) => 0,
+        Err(e) => {
+            eprintln!("loadtest: {}", e);
+            1
+        }
+    }
+}
+
+// parse_range parses a range of arities, such as '0-8', which must be non-empty and within the
+// arities the server generates.
+fn parse_range(s: &str) -> Option<std::ops::RangeInclusive<u64>> {
+    let (from, to) = s.split_once('-')?;
+    let (from, to) = (from.parse().ok()?, to.parse().ok()?);
+    if from > to || to > crate::family::MAX_ARITY {
+        return None;
+    }
+    Some(from..=to)
+}
+
+// run runs both phases, printing a report of each, and returns the number of failed requests.
+fn run(
+    arities: std::ops::RangeInclusive<u64>,
+    sessions: usize,
+    concurrency: usize,
+    servers: usize,
+) -> Result<usize, anyhow::Error> {
+    let dir = tempfile::TempDir::new()?;
+    let servers = (0..servers)
+        .map(|i| Server::start(dir.path(), &format!("server{}.log", i)))
+        .collect::<Result<Vec<_>, _>>()?;
+    let pids = servers.iter().map(|s| s.child.id()).collect::<Vec<_>>();
+    let addrs = servers.iter().map(|s| s.addr).collect::<Vec<_>>();
+    for server in &servers {
+        println!("server pid {} on {}", server.child.id(), server.addr);
+    }
+    println!(
+        "{} sessions per phase over arities {}-{}, {} at a time",
+        sessions,
+        arities.start(),
+        arities.end(),
+        concurrency
+    );
+
+    let arities = arities.collect::<Vec<_>>();
+    let mut failed = 0;
+    for phase in ["cold", "warm"] {
+        let sampler = Sampler::start(pids.clone());
+        let results = drive(&addrs, &arities, sessions, concurrency);
+        let usage = sampler.stop();
+
+        println!();
+        println!("{}:", phase);
+        println!(
+            "  {:<16} {:>8} {:>8} {:>10} {:>10}",
+            "request", "count", "errors", "p50", "p99"
+        );
+        for (kind, samples) in &results {
+            let errors = samples.iter().filter(|s| s.is_err()).count();
+            let mut latencies = samples
+                .iter()
+                .filter_map(|s| s.as_ref().ok())
+                .cloned()
+                .collect::<Vec<_>>();
+            latencies.sort();
+            println!(
+                "  {:<16} {:>8} {:>8} {:>10} {:>10}",
+                kind,
+                samples.len(),
+                errors,
+                format_latency(percentile(&latencies, 0.5)),
+                format_latency(percentile(&latencies, 0.99))
+            );
+            for e in samples.iter().filter_map(|s| s.as_ref().err()).take(3) {
+                println!("    error: {}", e);
+            }
+            failed += errors;
+        }
+        println!(
+            "  subprocesses: {} at peak, {} in all",
+            usage.peak_children, usage.total_children
+        );
+        println!(
+            "  memory: {:.1} MiB rss at peak",
+            usage.peak_rss_kib as f64 / 1024.0
+        );
+        if phase == "cold" && servers.len() > 1 {
+            let generated = generations(dir.path(), servers.len())?;
+            let duplicated = generated.values().filter(|&&count| count > 1).count();
+            println!(
+                "  generated: {} repos, {} of them more than once",
+                generated.len(),
+                duplicated
+            );
+            failed += duplicated;
+        }
+    }
+    Ok(failed)
+}
+
+// generations counts how many times each repo was generated, from the servers' logs.
+fn generations(
+    dir: &std::path::Path,
+    servers: usize,
+) -> Result<std::collections::HashMap<String, usize>, anyhow::Error> {
+    let mut generated = std::collections::HashMap::new();
+    for i in 0..servers {
+        let log = std::fs::read_to_string(dir.join(format!("server{}.log", i)))?;
+        for name in log.lines().filter_map(|l| l.strip_prefix("generating: ")) {
+            *generated.entry(name.to_string()).or_insert(0) += 1;
+        }
+    }
+    Ok(generated)
+}
+
+type Samples = Vec<Result<Duration, String>>;
+
+// drive runs sessions sessions, concurrency at a time, taking turns between the servers at addrs,
+// returning the latency of each request by type.
+fn drive(
+    addrs: &[SocketAddr],
+    arities: &[u64],
+    sessions: usize,
+    concurrency: usize,
+) -> Vec<(&'static str, Samples)> {
+    let next = AtomicUsize::new(0);
+    let info_refs = Mutex::new(Vec::new());
+    let upload_pack = Mutex::new(Vec::new());
+
+    std::thread::scope(|s| {
+        for _ in 0..concurrency {
+            s.spawn(|| loop {
+                let i = next.fetch_add(1, Ordering::Relaxed);
+                if i >= sessions {
+                    return;
+                }
+                // each round of arities goes to the next server, so every server asks for every
+                // repo, whatever the number of each
+                let addr = addrs[(i / arities.len()) % addrs.len()];
+                let repo = format!(
+                    "/{}.git",
+                    crate::tuple_repo_name(arities[i % arities.len()])
+                );
+
+                let start = Instant::now();
+                let refs = get_info_refs(addr, &repo);
+                info_refs.lock().unwrap().push(
+                    refs.as_ref()
+                        .map(|_| start.elapsed())
+                        .map_err(|e| e.to_string()),
+                );
+                let head = match refs {
+                    Ok(head) => head,
+                    Err(_) => continue,
+                };
+
+                let start = Instant::now();
+                let res = post_upload_pack(addr, &repo, &head).map(|_| start.elapsed());
+                upload_pack
+                    .lock()
+                    .unwrap()
+                    .push(res.map_err(|e| e.to_string()));
+            });
+        }
+    });
+
+    vec![
+        ("info/refs", info_refs.into_inner().unwrap()),
+        ("git-upload-pack", upload_pack.into_inner().unwrap()),
+    ]
+}
+
+// get_info_refs fetches the ref advertisement for the repo, returning the first ref's id.
+fn get_info_refs(addr: SocketAddr, repo: &str) -> Result<String, anyhow::Error> {
+    let body = http(
+        addr,
+        "GET",
+        &format!("{}/info/refs?service=git-upload-pack", repo),
+        &[],
+    )?;
+    // skip the '# service=git-upload-pack' line and the flush after it
+    let mut rest = &body[..];
+    for _ in 0..3 {
+        let len = std::str::from_utf8(rest.get(..4).unwrap_or_default())
+            .ok()
+            .and_then(|l| usize::from_str_radix(l, 16).ok())
+            .ok_or_else(|| anyhow::anyhow!("malformed ref advertisement"))?;
+        if len > 4 + 40 && rest.len() >= len && !rest[4..].starts_with(b"#") {
+            return Ok(String::from_utf8_lossy(&rest[4..4 + 40]).into_owned());
+        }
+        rest = rest.get(len.max(4)..).unwrap_or_default();
+    }
+    anyhow::bail!("no refs advertised")
+}
+
+// post_upload_pack asks for a pack of everything reachable from want, as a fresh clone does.
+fn post_upload_pack(addr: SocketAddr, repo: &str, want: &str) -> Result<(), anyhow::Error> {
+    let want = format!("want {}\n", want);
+    let request = format!("{:04x}{}00000009done\n", 4 + want.len(), want);
+    let body = http(
+        addr,
+        "POST",
+        &format!("{}/git-upload-pack", repo),
+        request.as_bytes(),
+    )?;
+    if !body.windows(4).any(|w| w == b"PACK") {
+        anyhow::bail!("response did not contain a pack");
+    }
+    Ok(())
+}
+
+// http makes a single HTTP/1.1 request, returning the body of a 200 response.
+fn http(addr: SocketAddr, method: &str, path: &str, body: &[u8]) -> Result<Vec<u8>, anyhow::Error> {
+    let mut stream = TcpStream::connect(addr)?;
+    write!(
+        stream,
+        "{} {} HTTP/1.1\r\nHost: {}\r\nConnection: close\r\nContent-Length: {}\r\n\r\n",
+        method,
+        path,
+        addr,
+        body.len()
+    )?;
+    stream.write_all(body)?;
+
+    let mut resp = Vec::new();
+    stream.read_to_end(&mut resp)?;
+    let header_end = resp
+        .windows(4)
+        .position(|w| w == b"\r\n\r\n")
+        .ok_or_else(|| anyhow::anyhow!("truncated response"))?;
+    let status = String::from_utf8_lossy(&resp[..header_end])
+        .lines()
+        .next()
+        .unwrap_or_default()
+        .to_string();
+    if !status.starts_with("HTTP/1.1 200 ") {
+        anyhow::bail!("{} {}: {}", method, path, status);
+    }
+    Ok(resp.split_off(header_end + 4))
+}
+
+fn percentile(sorted: &[Duration], p: f64) -> Option<Duration> {
+    if sorted.is_empty() {
+        return None;
+    }
+    Some(sorted[((sorted.len() - 1) as f64 * p).round() as usize])
+}
+
+fn format_latency(d: Option<Duration>) -> String {
+    match d {
+        Some(d) => format!("{:.1}ms", d.as_secs_f64() * 1000.0),
+        None => "-".into(),
+    }
+}
+
+// Server is a server running from this binary, killed when dropped.
+struct Server {
+    child: std::process::Child,
+    addr: SocketAddr,
+}
+
+impl Server {
+    // start starts a server in dir, logging its stdout to the file log in dir.
+    fn start(dir: &std::path::Path, log: &str) -> Result<Server, anyhow::Error> {
+        // find a free port; there's a small window for something else to take it, but this is a
+        // benchmark, not production
+        let addr = std::net::TcpListener::bind("127.0.0.1:0")?.local_addr()?;
+        let child = std::process::Command::new(std::env::current_exe()?)
+            .current_dir(dir)
+            .env("PORT", addr.port().to_string())
+            .env_remove("UPSTREAM")
+            .stdout(std::fs::File::create(dir.join(log))?)
+            .stderr(std::process::Stdio::null())
+            .spawn()?;
+        let server = Server { child, addr };
+
+        let deadline = Instant::now() + Duration::from_secs(10);
+        while TcpStream::connect(addr).is_err() {
+            if Instant::now() > deadline {
+                anyhow::bail!("server didn't start listening on {}", addr);
+            }
+            std::thread::sleep(Duration::from_millis(50));
+        }
+        Ok(server)
+    }
+}
+
+impl Drop for Server {
+    fn drop(&mut self) {
+        let _ = self.child.kill();
+        let _ = self.child.wait();
+    }
+}
+
+// Usage is what a Sampler saw of a process while it ran.
+#[derive(Default)]
+struct Usage {
+    peak_children: usize,
+    total_children: usize,
+    peak_rss_kib: u64,
+}
+
+// Sampler polls /proc for processes' children and memory use, on a thread of its own, adding up
+// what it sees of each process.
+struct Sampler {
+    stop: Arc<AtomicBool>,
+    thread: std::thread::JoinHandle<Usage>,
+}
+
+impl Sampler {
+    fn start(pids: Vec<u32>) -> Sampler {
+        let stop = Arc::new(AtomicBool::new(false));
+        let thread = std::thread::spawn({
+            let stop = stop.clone();
+            move || {
+                let mut usage = Usage::default();
+                let mut seen = std::collections::HashSet::new();
+                while !stop.load(Ordering::Relaxed) {
+                    let children = pids
+                        .iter()
+                        .flat_map(|&pid| children(pid))
+                        .collect::<Vec<_>>();
+                    usage.peak_children = usage.peak_children.max(children.len());
+                    seen.extend(children);
+                    let rss = pids.iter().map(|&pid| rss_kib(pid).unwrap_or(0)).sum();
+                    usage.peak_rss_kib = usage.peak_rss_kib.max(rss);
+                    std::thread::sleep(Duration::from_millis(5));
+                }
+                usage.total_children = seen.len();
+                usage
+            }
+        });
+        Sampler { stop, thread }
+    }
+
+    fn stop(self) -> Usage {
+        self.stop.store(true, Ordering::Relaxed);
+        self.thread.join().unwrap_or_default()
+    }
+}
+
+// children lists the pids of pid's child processes. Subprocesses which start and exit between
+// samples aren't seen, so counts are a lower bound.
+fn children(pid: u32) -> Vec<u32> {
+    let entries = match std::fs::read_dir("/proc") {
+        Ok(entries) => entries,
+        Err(_) => return Vec::new(),
+    };
+    entries
+        .filter_map(|e| e.ok()?.file_name().to_str()?.parse::<u32>().ok())
+        .filter(|child| {
+            // the parent pid is the second field after the command, which is in parens
+            let stat = std::fs::read_to_string(format!("/proc/{}/stat", child)).unwrap_or_default();
+            stat.rsplit_once(')')
+                .and_then(|(_, rest)| rest.split_whitespace().nth(1)?.parse::<u32>().ok())
+                == Some(pid)
+        })
+        .collect()
+}
+
+fn rss_kib(pid: u32) -> Option<u64> {
+    let status = std::fs::read_to_string(format!("/proc/{}/status", pid)).ok()?;
+    status
+        .lines()
+        .find_map(|l| l.strip_prefix("VmRSS:"))?
+        .trim()
+        .trim_end_matches("kB")
+        .trim()
+        .parse()
+        .ok()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_ranges() {
+        assert_eq!(parse_range("0-8"), Some(0..=8));
+        assert_eq!(parse_range("3-3"), Some(3..=3));
+        assert_eq!(parse_range("0-256"), Some(0..=256));
+        for s in ["8-0", "0-257", "8", "-8", "a-8"] {
+            assert_eq!(parse_range(s), None, "{}", s);
+        }
+    }
+}
@@ -0,0 +1,50 @@
+// Advisory file locks, so that several server processes sharing one repos directory take turns
+// generating each repo, rather than all generating it at once and racing to rename it into place.
+//
+// Locks are flock(2) locks, which belong to an open file rather than a process, so they also keep
+// threads of the same process apart, and are released by the kernel if their holder dies. They're
+// only advisory: anything else writing to repos/ is on its own.
+
+use std::fs::File;
+use std::os::unix::io::AsRawFd;
+use std::path::{Path, PathBuf};
+
+// A Lock is an exclusive lock on a lock file, held until it's dropped.
+pub struct Lock {
+    file: File,
+}
+
+// exclusive blocks until it holds the lock on the file at path, creating the file if need be.
+// Lock files are left in place, since removing one while another process waits on it would let a
+// third process lock a new file of the same name at the same time.
+pub fn exclusive(path: &Path) -> Result<Lock, std::io::Error> {
+    let file = std::fs::OpenOptions::new()
+        .create(true)
+        .write(true)
+        .open(path)?;
+    loop {
+        // SAFETY: flock only operates on the descriptor, which file keeps open
+        if unsafe { libc::flock(file.as_raw_fd(), libc::LOCK_EX) } == 0 {
+            return Ok(Lock { file });
+        }
+        let err = std::io::Error::last_os_error();
+        if err.kind() != std::io::ErrorKind::Interrupted {
+            return Err(err);
+        }
+    }
+}
+
+// path returns the lock file guarding the creation of the repo at repo, which sits beside it.
+pub fn path(repo: &Path) -> PathBuf {
+    let mut path = repo.as_os_str().to_owned();
+    path.push(".lock");
+    path.into()
+}
+
+impl Drop for Lock {
+    fn drop(&mut self) {
+        // closing the file would release the lock anyway; this just makes it explicit
+        // SAFETY: as in exclusive
+        unsafe { libc::flock(self.file.as_raw_fd(), libc::LOCK_UN) };
+    }
+}
@@ -1,5 +1,5 @@
 use std::collections::HashMap;
-use std::io::{Read, Write};
+use std::io::Write;
 
 use anyhow::Context;
 use axum::{
@@ -9,16 +9,50 @@ use axum::{
     response::Html,
     response::IntoResponse,
     routing::get,
-    Router,
+    Json, Router,
 };
 use maud::html;
 use tower_http::trace::TraceLayer;
 use tracing_subscriber::{layer::SubscriberExt, util::SubscriberInitExt};
 
+mod api;
+mod badge;
+mod decorate;
+mod family;
+mod gotype;
+mod highlight;
+mod loadtest;
+mod lock;
+mod matcher;
+mod matrix;
+mod mirror;
+mod mock;
+mod parse;
+mod pool;
+mod ring;
+mod signal;
+mod smallvec;
+mod spool;
+mod stubs;
+mod tuple;
+mod units;
+mod vector;
+mod verify;
+mod vulndb;
+
 const SOURCE_CODE: &[u8] = include_repo::include_repo!();
 
 #[tokio::main]
 async fn main() -> Result<(), axum::http::Error> {
+    let args = std::env::args().collect::<Vec<_>>();
+    match args.get(1).map(String::as_str) {
+        Some("verify") => std::process::exit(verify::main(&args[2..])),
+        Some("loadtest") => std::process::exit(loadtest::main(&args[2..])),
+        Some("replay") => std::process::exit(spool::main(&args[2..])),
+        Some("stubs") => std::process::exit(stubs::main(&args[2..])),
+        _ => {}
+    }
+
     tracing_subscriber::registry()
         .with(tracing_subscriber::EnvFilter::new(
             std::env::var("RUST_LOG")
@@ -38,6 +72,16 @@ async fn main() -> Result<(), axum::http::Error> {
                 "Welcome to this site with some golang packages! Check em out:" br;
                 ul {
                     li { a href="/tuple" { "n-ary generic tuple" } }
+                    li { a href="/decorate" { "function decorators" } }
+                    li { a href="/match" { "typed regexp matches" } }
+                    li { a href="/matrix" { "fixed-size matrix" } }
+                    li { a href="/mock" { "function mocks and spies" } }
+                    li { a href="/parse" { "parser combinators" } }
+                    li { a href="/ring" { "fixed-capacity ring buffer" } }
+                    li { a href="/signal" { "n-argument signal" } }
+                    li { a href="/smallvec" { "small vector" } }
+                    li { a href="/units" { "dimensional quantities" } }
+                    li { a href="/vector" { "fixed-size vector" } }
                 }
             }
             h3 { "FAQ" }
@@ -48,6 +92,21 @@ async fn main() -> Result<(), axum::http::Error> {
                 p {
                     "On github " a href="https://github.com/euank/pkg.golang.fail" { "here" } ", or as an archive " a href="/source.tar.gz" { "here" } "." br;
                 }
+                h4 { "How do I know if I'm using a broken version of a package?" }
+                p {
+                    "Defective versions are published in a vulnerability database, so " code { "govulncheck -db https://pkg.golang.fail/vulndb ./..." } " will tell you." br;
+                }
+                h4 { "Is there an API?" }
+                p {
+                    "Yes, a JSON one, with CORS headers, under " code { "/api/v1" } ". It's described by " a href="/api/v1/openapi.json" { "an OpenAPI document" } "." br;
+                }
+                h4 { "Can I show off which version I'm using?" }
+                p {
+                    "Sure, there are SVG badges for any module, such as " img src="/badge/tuple/3.svg" alt="tuple/3 version";
+                    ", from " code { "/badge/tuple/3.svg" } ". " code { "/badge/tuple/3/template.svg" } " fingerprints the latest version's files, and "
+                    code { "/badge/tuple/3/clones.svg" } " counts its clones and fetches since the server last restarted. "
+                    "Counts are kept in memory by each server process, so they start again from zero on restart, and with more than one server, each counts only the clones it served." br;
+                }
                 h4 { "Should I use any of these packages?" }
                 p { a href="https://youtu.be/gIVGftIWt4s?t=2" { "I just don't know" } }
             };
@@ -59,19 +118,107 @@ async fn main() -> Result<(), axum::http::Error> {
         .route("/source.tar.gz", get(source_code))
         .route("/tuple", get(tuple))
         .route("/tuple/:n/tuple", get(tuple_n))
+        .route("/tuple/:n/schema.json", get(tuple_n_schema))
+        .route("/tuple/:n/types.ts", get(tuple_n_typescript))
+        .route("/tuple/:n/types.py", get(tuple_n_python))
+        .route(
+            "/tuple/:n/tuple/*rest",
+            get(tuple_n_major_handler).post(tuple_n_major_handler),
+        )
         .route(
             "/tuple/:n/tuple.git/*tree",
             get(tuple_n_git_handler).post(tuple_n_git_handler),
         )
-        .layer(TraceLayer::new_for_http());
+        .route("/units", get(units_page))
+        .route("/vector", get(vector))
+        .route("/ring", get(ring))
+        .route("/decorate", get(decorate))
+        .route("/mock", get(mock))
+        .route("/parse", get(parse_page))
+        .route("/signal", get(signal))
+        .route("/smallvec", get(smallvec))
+        .route("/match", get(match_page))
+        .route("/matrix", get(matrix))
+        .route(
+            "/vulndb/index/db.json",
+            get(|| async { Json(vulndb::db_index()) }),
+        )
+        .route(
+            "/vulndb/index/modules.json",
+            get(|| async { Json(vulndb::modules_index()) }),
+        )
+        .route(
+            "/vulndb/index/vulns.json",
+            get(|| async { Json(vulndb::vulns_index()) }),
+        )
+        .route("/vulndb/ID/:id", get(vulndb_entry))
+        .route("/metrics", get(metrics))
+        .route("/badge/*rest", get(badge::badge))
+        .nest("/api/v1", api::router());
+
+    // every other family's modules, under the first element of their paths, which some share
+    let mut prefixes = family::FAMILIES
+        .iter()
+        .filter(|f| f.routed)
+        .map(|f| f.path.split('/').nth(1).unwrap())
+        .collect::<Vec<_>>();
+    prefixes.sort();
+    prefixes.dedup();
+    for prefix in prefixes {
+        app = app.route(
+            &format!("/{}/*rest", prefix),
+            get(module_handler).post(module_handler),
+        );
+    }
+    app = app.layer(TraceLayer::new_for_http());
 
-    axum::Server::bind(&std::net::SocketAddr::from(([0, 0, 0, 0], 8080)))
+    let port = match std::env::var("PORT") {
+        Ok(port) => port.parse().expect("PORT must be a port number"),
+        Err(_) => 8080,
+    };
+    if let Some(upstream) = mirror::upstream() {
+        println!("mirroring repos from {}", upstream);
+    }
+
+    axum::Server::bind(&std::net::SocketAddr::from(([0, 0, 0, 0], port)))
         .serve(app.into_make_service())
         .await
         .unwrap();
     Ok(())
 }
 
+// metrics reports the state of the generation pool, in the Prometheus text format.
+async fn metrics() -> impl IntoResponse {
+    let pool = pool::pool();
+    let gauges = [
+        (
+            "generation_queued",
+            "Repos waiting for a turn to be generated.",
+            pool.queued(),
+        ),
+        (
+            "generation_running",
+            "Repos being generated.",
+            pool.running(),
+        ),
+        (
+            "generation_limit",
+            "The most repos generated at once.",
+            pool.limit(),
+        ),
+    ];
+    let body = gauges
+        .iter()
+        .map(|(name, help, value)| {
+            format!(
+                "# HELP {} {}\n# TYPE {} gauge\n{} {}\n",
+                name, help, name, name, value
+            )
+        })
+        .collect::<String>();
+    ([("Content-Type", "text/plain; version=0.0.4")], body)
+}
+
 async fn source_code() -> impl IntoResponse {
     axum::http::Response::builder()
         .header("Content-Type", "application/gzip")
@@ -80,31 +227,403 @@ async fn source_code() -> impl IntoResponse {
         .unwrap()
 }
 
+const RING_EXAMPLE: &str = include_str!("./ring_example.go");
+
+async fn ring() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "fixed-capacity ring buffer" }
+        p {
+            "This package provides ring buffers with a capacity fixed at compile time, stored inline in an array, so they never allocate and can be copied around as plain values." br;
+            "Pushing to a full ring either overwrites its oldest value, or in " code { "Reject" } " mode, fails:"
+            (highlight::go(RING_EXAMPLE))
+        }
+        p {
+            "Import rings with " code { "pkg.golang.fail/ring/$NUM" } " where " code { "$NUM" } " is the capacity."
+        }
+    }.into_string())
+}
+
+const DECORATE_EXAMPLE: &str = include_str!("./decorate_example.go");
+
+async fn decorate() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "function decorators" }
+        p {
+            "This package provides " code { "Retry" } ", " code { "Timeout" } ", " code { "Logged" } " and " code { "RateLimited" } " decorators for functions that take a context and return an error, which keep the exact signature of the function they decorate." br;
+            "Each signature arity is its own module, so there's no " code { "any" } " in sight:"
+            (highlight::go(DECORATE_EXAMPLE))
+        }
+        p {
+            "Import decorators with " code { "pkg.golang.fail/decorate/$IN/$OUT" } " where " code { "$IN" } " is the number of arguments after the context, and " code { "$OUT" } " is the number of results before the error."
+        }
+    }.into_string())
+}
+
+const MOCK_EXAMPLE: &str = include_str!("./mock_example.go");
+
+async fn mock() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "function mocks and spies" }
+        p {
+            "This package provides mocks of functions, which record the arguments of every call as a " a href="/tuple" { "tuple" } ", and return canned results or call through to the function being spied on." br;
+            "Each signature arity is its own module, so mocks keep the exact type of the function they stand in for:"
+            (highlight::go(MOCK_EXAMPLE))
+        }
+        p {
+            "Import mocks with " code { "pkg.golang.fail/mock/$IN/$OUT" } " where " code { "$IN" } " and " code { "$OUT" } " are the number of arguments and results of the function." br;
+            "Mocks are safe for concurrent use."
+        }
+    }.into_string())
+}
+
+const PARSE_EXAMPLE: &str = include_str!("./parse_example.go");
+
+async fn parse_page() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "parser combinators" }
+        p {
+            "This package provides parser combinators, with a " code { "Seq" } " which parses one thing after another into a tuple of whatever types each parser returns." br;
+            "Go doesn't have variadic generics, so each number of parsers is its own module, while the parsers themselves, along with " code { "Map" } ", " code { "Or" } " and " code { "Many" } ", are in " code { (parse::PARSER_MODULE_PATH) } ":"
+            (highlight::go(PARSE_EXAMPLE))
+        }
+        p {
+            "Import sequences with " code { "pkg.golang.fail/parse/$NUM/parse" } " where " code { "$NUM" } " is the number of parsers." br;
+            "Parse errors give the line and column of the furthest point any parser got to, with everything which would have been accepted there."
+        }
+    }.into_string())
+}
+
+const SIGNAL_EXAMPLE: &str = include_str!("./signal_example.go");
+
+async fn signal() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "n-argument signal" }
+        p {
+            "This package provides signals, lists of callbacks with typed arguments which are all called when the signal is emitted." br;
+            "Go doesn't have variadic generics, so each number of arguments is its own module, just like tuples:"
+            (highlight::go(SIGNAL_EXAMPLE))
+        }
+        p {
+            "Import signals with " code { "pkg.golang.fail/signal/$NUM/signal" } " where " code { "$NUM" } " is the number of arguments the callbacks take." br;
+            "Signals are safe for concurrent use, including connecting and disconnecting callbacks while the signal is being emitted."
+        }
+    }.into_string())
+}
+
+const SMALLVEC_EXAMPLE: &str = include_str!("./smallvec_example.go");
+
+async fn smallvec() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "small vector" }
+        p {
+            "This package provides a growable vector which stores its first n values inline, and only allocates once it grows past them." br;
+            "When most of your lists are short, that saves an allocation per list, and the module includes benchmarks against plain slices to check it's worth it (" code { "go test -bench . pkg.golang.fail/smallvec/$NUM" } "):"
+            (highlight::go(SMALLVEC_EXAMPLE))
+        }
+        p {
+            "Import small vectors with " code { "pkg.golang.fail/smallvec/$NUM" } " where " code { "$NUM" } " is the number of values stored inline."
+        }
+    }.into_string())
+}
+
+const UNITS_EXAMPLE: &str = include_str!("./units_example.go");
+
+async fn units_page() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "dimensional quantities" }
+        p {
+            "This package provides a float64 quantity type for every dimension, given as exponents of metres, kilograms and seconds, so that adding metres to seconds doesn't compile, while multiplying or dividing them gives a quantity of the right dimension:"
+            (highlight::go(UNITS_EXAMPLE))
+        }
+        p {
+            "Import quantities with " code { "pkg.golang.fail/units/$M/$KG/$S/units" } " where " code { "$M" } ", " code { "$KG" } " and " code { "$S" } " are the exponents of metres, kilograms and seconds, such as " code { "1/0/n2" } " for acceleration, where negative exponents are spelled with an " code { "n" } "." br;
+            "Products and quotients are in " code { "pkg.golang.fail/units/$M/$KG/$S/mul/$M/$KG/$S/mul" } " and " code { "pkg.golang.fail/units/$M/$KG/$S/div/$M/$KG/$S/div" } ", whose " code { "Mul" } " and " code { "Div" } " take a quantity of the first dimension and one of the second." br;
+            "Quantities print with their unit, such as " code { "9.81 m·s⁻²" } "."
+        }
+    }.into_string())
+}
+
+const MATCH_EXAMPLE: &str = include_str!("./match_example.go");
+
+// match_page is the page for the match family, which can't be called match
+async fn match_page() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "typed regexp matches" }
+        p {
+            "This package provides regexps whose capture groups are parsed into a tuple of whatever types you ask for, rather than a slice of strings to index and " code { "strconv" } " yourself:"
+            (highlight::go(MATCH_EXAMPLE))
+        }
+        p {
+            "Import matches with " code { "pkg.golang.fail/match/$NUM/match" } " where " code { "$NUM" } " is the number of capture groups." br;
+            "Groups are parsed with " code { "strconv" } ", " code { "time.ParseDuration" } ", or " code { "encoding.TextUnmarshaler" } ", depending on their type, and unsupported types are reported by " code { "Compile" } "."
+        }
+    }.into_string())
+}
+
+const MATRIX_EXAMPLE: &str = include_str!("./matrix_example.go");
+
+async fn matrix() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        (highlight::head())
+        h2 { "fixed-size matrix" }
+        p {
+            "This package provides r×c matrices of numbers, with their size checked at compile time." br;
+            "As with tuples, each size of matrix is its own go module. Mul and Transpose return a matrix of a different size, so they live in the module of the size they return:"
+            (highlight::go(MATRIX_EXAMPLE))
+        }
+        p {
+            "Import matrices with " code { "pkg.golang.fail/matrix/$ROWS/$COLS" } ". Their rows and columns are " a href="/vector" { "vectors" } ", and they convert to and from " a href="/tuple" { "tuples" } " of their rows."
+        }
+    }.into_string())
+}
+
+async fn vector() -> impl IntoResponse {
+    Html(html! {
+        (maud::DOCTYPE)
+        h2 { "fixed-size vector" }
+        p {
+            "This package provides n-dimensional vectors of numbers, as the rows and columns of " a href="/matrix" { "matrices" } ", and converts them to and from " a href="/tuple" { "tuples" } "." br;
+            "Import vectors with " code { "pkg.golang.fail/vector/$NUM" } "."
+        }
+    }.into_string())
+}
+
+// split_git_path splits the part of a path after a module's last parameter, such as '/3' or
+// '/3.git/info/refs', into the parameter and, for git requests, the path within the repo.
+// The router can't match a parameter directly followed by '.git', so families whose module paths
+// end in a parameter route everything below it to one handler and split it here.
+fn split_git_path(rest: &str) -> (&str, Option<&str>) {
+    let rest = rest.trim_start_matches('/');
+    match rest.find(".git/") {
+        Some(i) => (&rest[..i], Some(&rest[i + ".git".len()..])),
+        None => (rest, None),
+    }
+}
+
+// serve_module responds to go-get and git requests for the generated module at
+// 'pkg.golang.fail/{name}', where tree is the path within the repo for git requests.
+// See init_repo for version and generate.
+async fn serve_module(
+    name: &str,
+    version: &'static str,
+    generate: impl Fn(&std::path::Path) -> Result<(), anyhow::Error> + Send + 'static,
+    tree: Option<&str>,
+    query: &HashMap<String, String>,
+    req: axum::http::Request<axum::body::Body>,
+) -> axum::response::Response {
+    let tree = match tree {
+        Some(tree) => tree,
+        None => {
+            return go_import(&format!("/{}", name), req.uri().query().unwrap_or(""))
+                .into_response()
+        }
+    };
+    capture_git(name, req, |req| async move {
+        let repo = ensure_repo(name, version, generate).await?;
+        serve_git(name, repo, tree, query, req).await
+    })
+    .await
+    .into_response()
+}
+
+// module_handler serves go-get and git requests for the modules of every routed family in
+// family::FAMILIES, such as 'vector/3' and 'vector/3.git/info/refs'.
+async fn module_handler(
+    Query(query): Query<HashMap<String, String>>,
+    req: axum::http::Request<axum::body::Body>,
+) -> impl IntoResponse {
+    let path = req.uri().path().to_string();
+    let (pkg, tree) = split_git_path(&path);
+    let module = match family::parse_module(pkg) {
+        Some(module) if module.family.routed => module,
+        _ => return (StatusCode::NOT_FOUND, "no such module").into_response(),
+    };
+    let version = module.repo_version();
+    let name = module.repo();
+    let generate = move |root: &std::path::Path| write_repo(root, module.repo_commits());
+    serve_module(&name, version, generate, tree, &query, req).await
+}
+
+async fn vulndb_entry(Path(id): Path<String>) -> impl IntoResponse {
+    match id.strip_suffix(".json").and_then(vulndb::entry) {
+        Some(entry) => Json(entry).into_response(),
+        None => (StatusCode::NOT_FOUND, "no such vulnerability").into_response(),
+    }
+}
+
 const TUPLE_EXAMPLE: &str = include_str!("./tuple_example.go");
 
-async fn tuple() -> impl IntoResponse {
+// tuple also shows exact go get and import lines for the arity in the 'n' parameter, or 3 by
+// default, or when there's no module of that arity.
+async fn tuple(Query(query): Query<HashMap<String, String>>) -> impl IntoResponse {
+    let n = query
+        .get("n")
+        .and_then(|n| n.parse::<u64>().ok())
+        .filter(|&n| n <= family::MAX_ARITY)
+        .unwrap_or(3);
     Html(html! {
         (maud::DOCTYPE)
+        (highlight::head())
         h2 { "n-ary generic tuple" }
         p {
-            "This package provides a set of n-ary generic tuple type, for all n! " br;
+            "This package provides a set of n-ary generic tuple type, for all n! (Well, up to " (family::MAX_ARITY) ".) " br;
             "Due to some fundamental limitations, we've ended up splitting it up as one N-ary tuple per unique go package, so you'll need a go.mod entry per n-tuple." br;
             br;
             "What does this look like in practice? Well, let's look at some sample code using this tuple type:"
-            pre {
-                (TUPLE_EXAMPLE)
-            }
+            (highlight::go(TUPLE_EXAMPLE))
         }
         p {
             "Import tuples with the pattern shown above, with " code { "pkg.golang.fail/tuple/$NUM/tuple" } " where " code { "$NUM" } " is the desired arity of the tuple type."
+            " For " (n) "-ary tuples (or " a href="?n=5" { "5-ary" } ", and so on), that's:"
+            (tuple_snippets(n, tuple::latest_major()))
+        }
+        p {
+            "Breaking changes to the tuple API will get a new major version, per go's semantic import versioning, imported with a suffix such as " code { (tuple::module_path(3, 2)) } ". There haven't been any so far." br;
+            "As of v1.1.0, " code { "JSONSchema" } " describes a tuple encoded as a JSON array of its elements. Tuples don't encode that way themselves: " code { "encoding/json" } " encodes them as objects with fields " code { "T0" } ", " code { "T1" } " and so on, so the schema is for tuples you encode as arrays yourself, such as " code { "[]any{t.T0, t.T1}" } ". "
+            "The same schema is available from " code { "/tuple/$NUM/schema.json?types=string,int" } ", given the go types of the elements. "
+            "The TypeScript type or Python annotation of tuples as " code { "encoding/json" } " encodes them, such as " code { "{ T0: string; T1: number }" } ", is available from " code { "/tuple/$NUM/types.ts" } " and " code { "/tuple/$NUM/types.py" } ", and all three from " code { "pkg-golang-fail stubs" } "." br;
+            "As of v1.2.0, comparable tuples can be interned with " code { "Intern" } ", which needs go 1.23 or later." br;
+            "As of v1.3.0, each module has a " code { "README.md" } " with its API and an example for its arity, and a " code { "CHANGELOG.md" } " of every release, so a clone or vendored copy explains itself." br;
+            "Individual files from any release can be fetched without cloning, such as " code { "/tuple/3/tuple/raw/v1.1.0/tuple.go" } "." br;
+            "Releases so far:"
+            ul {
+                @for release in tuple::RELEASES {
+                    li { code { (release.version) } ": " (release.message) }
+                }
+            }
         }
     }.into_string())
 }
 
+// tuple_n_schema returns the JSON Schema that JSONSchema returns, as of v1.1.0, for an n-ary tuple
+// encoded as a JSON array, as callers may encode tuples themselves (encoding/json encodes them as
+// objects), with the element types given as a comma separated list of go types in the 'types'
+// parameter.
+async fn tuple_n_schema(
+    Path(n): Path<u64>,
+    Query(query): Query<HashMap<String, String>>,
+) -> impl IntoResponse {
+    let types = match tuple_n_types(n, &query) {
+        Ok(types) => types,
+        Err(e) => return (StatusCode::BAD_REQUEST, e.to_string()).into_response(),
+    };
+    let elems = types.iter().map(|t| t.json_schema()).collect();
+    Json(gotype::tuple_json_schema(elems)).into_response()
+}
+
+// tuple_n_typescript and tuple_n_python return the type of an n-ary tuple as encoding/json encodes
+// it, an object with a field per element, as a TypeScript type and a Python annotation
+// respectively.
+async fn tuple_n_typescript(
+    Path(n): Path<u64>,
+    Query(query): Query<HashMap<String, String>>,
+) -> impl IntoResponse {
+    match tuple_n_types(n, &query) {
+        Ok(types) => plain_text(gotype::tuple_typescript(&types)),
+        Err(e) => (StatusCode::BAD_REQUEST, e.to_string()).into_response(),
+    }
+}
+
+async fn tuple_n_python(
+    Path(n): Path<u64>,
+    Query(query): Query<HashMap<String, String>>,
+) -> impl IntoResponse {
+    match tuple_n_types(n, &query) {
+        Ok(types) => plain_text(gotype::tuple_python(&types)),
+        Err(e) => (StatusCode::BAD_REQUEST, e.to_string()).into_response(),
+    }
+}
+
+// tuple_n_types parses the 'types' parameter for an n-ary tuple, defaulting to n 'any's.
+fn tuple_n_types(
+    n: u64,
+    query: &HashMap<String, String>,
+) -> Result<Vec<gotype::GoType>, anyhow::Error> {
+    if n > gotype::MAX_ELEMS {
+        anyhow::bail!(
+            "tuples of more than {} elements aren't supported",
+            gotype::MAX_ELEMS
+        );
+    }
+    let types = gotype::parse_list(query.get("types").map(|s| s.as_str()).unwrap_or(""))?;
+    if types.is_empty() {
+        return Ok((0..n).map(|_| gotype::GoType::Any).collect());
+    }
+    if types.len() as u64 != n {
+        anyhow::bail!("expected {} types, got {}", n, types.len());
+    }
+    Ok(types)
+}
+
+fn plain_text(body: String) -> axum::response::Response {
+    ([("Content-Type", "text/plain; charset=utf-8")], body).into_response()
+}
+
+// tuple_n responds to go-get requests for the n-ary tuple modules, and shows browsers how to get
+// the module they asked for.
 async fn tuple_n(req: axum::http::Request<axum::body::Body>) -> impl IntoResponse {
+    // major version module paths, such as '/tuple/3/tuple/v2', share the repo at the v1 path
     let path = req.uri().path();
+    let (path, major) = match path.rsplit_once('/') {
+        Some((root, major)) => match tuple::parse_major(major) {
+            Some(major) => (root, major),
+            None => (path, 1),
+        },
+        None => (path, 1),
+    };
     let query = req.uri().query().unwrap_or("");
+    let n = path.split('/').nth(2).and_then(|n| n.parse::<u64>().ok());
+    match n {
+        Some(n) if query != "go-get=1" => Html(
+            html! {
+                (maud::DOCTYPE)
+                (highlight::head())
+                h2 { (n) "-ary generic tuple" }
+                p {
+                    "Get the module, and import it, with:"
+                    (tuple_snippets(n, major))
+                    "See " a href="/tuple" { "the tuple page" } " for how to use it."
+                }
+            }
+            .into_string(),
+        ),
+        _ => go_import(path, query),
+    }
+}
+
+// tuple_snippets renders copyable go get and import lines for the latest release of the n-ary tuple
+// with the given major version.
+fn tuple_snippets(n: u64, major: u64) -> maud::Markup {
+    let path = tuple::module_path(n, major);
+    let release = tuple::RELEASES.iter().rfind(|r| r.major() == major);
+    html! {
+        @if let Some(release) = release {
+            (highlight::snippet(&format!("go get {}@{}", path, release.version)))
+        }
+        (highlight::snippet(&format!("import \"{}\"", path)))
+    }
+}
 
+// go_import responds to go-get requests for the module at path, served from the repo at
+// 'path.git'.
+fn go_import(path: &str, query: &str) -> Html<String> {
     if query == "go-get=1" {
         Html(html! {
             (maud::DOCTYPE)
@@ -115,12 +634,183 @@ async fn tuple_n(req: axum::http::Request<axum::body::Body>) -> impl IntoRespons
     }
 }
 
+// tuple_n_major_handler handles paths below the v1 module path, which are major-version module paths,
+// i.e. '/tuple/:n/tuple/v2' for go-get, and '/tuple/:n/tuple/v2.git/...' for git.
+// All major versions live in the same repo, so both of these are served from the same place as
+// the v1 module.
+// It also serves raw files from the repo, at '/tuple/:n/tuple/raw/<ref>/<file>'.
+async fn tuple_n_major_handler(
+    Path((n, rest)): Path<(u64, String)>,
+    q: Query<HashMap<String, String>>,
+    req: axum::http::Request<axum::body::Body>,
+) -> impl IntoResponse {
+    if n > family::MAX_ARITY {
+        return (StatusCode::NOT_FOUND, "no such tuple").into_response();
+    }
+    let rest = rest.trim_start_matches('/');
+    let (major, tree) = match rest.find('/') {
+        Some(i) => rest.split_at(i),
+        None => (rest, ""),
+    };
+
+    if major == "raw" {
+        return tuple_n_raw(n, tree.trim_start_matches('/'))
+            .await
+            .into_response();
+    }
+    if let Some(major) = major.strip_suffix(".git") {
+        if tuple::parse_major(major).is_some() && !tree.is_empty() {
+            return tuple_n_git_handler(Path((n, tree.to_string())), q, req)
+                .await
+                .into_response();
+        }
+    } else if tuple::parse_major(major).is_some() && tree.is_empty() {
+        return tuple_n(req).await.into_response();
+    }
+
+    (StatusCode::NOT_FOUND, "no such major version").into_response()
+}
+
+// tuple_n_raw responds with the contents of a file in the n-ary tuple repo, given a path of the
+// form '<ref>/<file>', where ref is a tag, branch or commit, such as 'v1.0.0' or 'refs/tags/v1.0.0'.
+async fn tuple_n_raw(n: u64, path: &str) -> axum::response::Response {
+    if !path.contains('/') {
+        return (StatusCode::NOT_FOUND, "expected raw/<ref>/<file>").into_response();
+    }
+    let repo = match tuple_repo(n).await {
+        Ok(repo) => repo,
+        Err(e) => return git_response(Err(e)).into_response(),
+    };
+    // libgit2 blocks, so read on the pool
+    let path = path.to_string();
+    let (file, contents) = match pool::pool().run(move || read_file(&repo, &path)).await {
+        Ok(Ok(Some(file))) => file,
+        Ok(Ok(None)) => return (StatusCode::NOT_FOUND, "no such ref or file").into_response(),
+        Ok(Err(e)) | Err(e) => return git_response(Err(e)).into_response(),
+    };
+
+    let content_type = if file.ends_with(".go") {
+        "text/x-go; charset=utf-8"
+    } else if file == "go.mod" || file == "LICENSE" {
+        "text/plain; charset=utf-8"
+    } else {
+        "application/octet-stream"
+    };
+    (
+        [
+            ("Content-Type", content_type),
+            ("X-Content-Type-Options", "nosniff"),
+        ],
+        contents,
+    )
+        .into_response()
+}
+
+// read_file returns the name and contents of the file at path, of the form '<rev>/<file>', in the
+// repo at root, or None if either doesn't exist. Revs may contain '/' themselves, so the longest
+// prefix of path which is a rev in the repo is used.
+fn read_file(
+    root: &std::path::Path,
+    path: &str,
+) -> Result<Option<(String, Vec<u8>)>, anyhow::Error> {
+    let repo = git2::Repository::open(root)?;
+    let mut commit = None;
+    for (i, _) in path.rmatch_indices('/') {
+        let (rev, file) = (&path[..i], &path[i + 1..]);
+        if rev.is_empty() || file.is_empty() {
+            continue;
+        }
+        let object = match repo.revparse_single(rev) {
+            Ok(object) => object,
+            Err(e)
+                if e.code() == git2::ErrorCode::NotFound
+                    || e.code() == git2::ErrorCode::InvalidSpec
+                    || e.code() == git2::ErrorCode::Ambiguous =>
+            {
+                continue
+            }
+            Err(e) => return Err(e.into()),
+        };
+        // revs such as 'v1.0.0:tuple.go' are blobs rather than commits
+        if let Ok(c) = object.peel_to_commit() {
+            commit = Some((c, file));
+            break;
+        }
+    }
+    let (commit, file) = match commit {
+        Some(commit) => commit,
+        None => return Ok(None),
+    };
+    let entry = match commit.tree()?.get_path(std::path::Path::new(file)) {
+        Ok(entry) => entry,
+        Err(e) if e.code() == git2::ErrorCode::NotFound => return Ok(None),
+        Err(e) => return Err(e.into()),
+    };
+    match entry.to_object(&repo)?.into_blob() {
+        Ok(blob) => Ok(Some((file.to_string(), blob.content().to_vec()))),
+        // a directory
+        Err(_) => Ok(None),
+    }
+}
+
 async fn tuple_n_git_handler(
     p: Path<(u64, String)>,
     q: Query<HashMap<String, String>>,
     req: axum::http::Request<axum::body::Body>,
 ) -> impl IntoResponse {
-    match tuple_n_git(p, q, req).await {
+    if p.0 .0 > family::MAX_ARITY {
+        return (StatusCode::NOT_FOUND, "no such tuple").into_response();
+    }
+    let name = tuple_repo_name(p.0 .0);
+    capture_git(&name, req, |req| tuple_n_git(p, q, req))
+        .await
+        .into_response()
+}
+
+// capture_git responds to a git request for the named repo with serve's response, saving the
+// request to the spool if serving it fails and capturing is enabled (see spool::dir).
+async fn capture_git<F>(
+    name: &str,
+    req: axum::http::Request<axum::body::Body>,
+    serve: impl FnOnce(axum::http::Request<axum::body::Body>) -> F,
+) -> axum::http::Response<axum::body::Full<axum::body::Bytes>>
+where
+    F: std::future::Future<
+        Output = Result<axum::http::Response<axum::body::Full<axum::body::Bytes>>, anyhow::Error>,
+    >,
+{
+    let spool = match spool::dir() {
+        Some(spool) => spool,
+        None => return git_response(serve(req).await),
+    };
+
+    // buffer the body, so that it's still around to be captured if serving the request fails
+    let (parts, mut body) = req.into_parts();
+    let mut data = Vec::new();
+    while let Some(chunk) = body.data().await {
+        match chunk {
+            Ok(chunk) => data.extend_from_slice(&chunk),
+            Err(e) => return git_response(Err(e.into())),
+        }
+    }
+    let mut capture = spool::Capture::new(name, &parts);
+    let req = axum::http::Request::from_parts(parts, axum::body::Body::from(data.clone()));
+
+    let res = serve(req).await;
+    if let Err(e) = &res {
+        capture.error = e.to_string();
+        match spool::save(&spool, &capture, &data) {
+            Ok(path) => println!("captured failing git request to {}", path.display()),
+            Err(e) => println!("could not capture failing git request: {}", e),
+        }
+    }
+    git_response(res)
+}
+
+fn git_response(
+    res: Result<axum::http::Response<axum::body::Full<axum::body::Bytes>>, anyhow::Error>,
+) -> axum::http::Response<axum::body::Full<axum::body::Bytes>> {
+    match res {
         Ok(r) => r,
         Err(e) => {
             println!("unhandled git error: {}", e);
@@ -137,33 +827,61 @@ async fn tuple_n_git_handler(
 async fn tuple_n_git(
     Path((n, path)): Path<(u64, String)>,
     Query(query): Query<HashMap<String, String>>,
-    mut req: axum::http::Request<axum::body::Body>,
+    req: axum::http::Request<axum::body::Body>,
 ) -> Result<axum::http::Response<axum::body::Full<axum::body::Bytes>>, anyhow::Error> {
     // serve up a git repo
     // total hack, _but_ I can't find a good in-memory git repo option, so serve em up via the
     // filesystem.
-    let repo = init_repo(n)?;
+    let repo = tuple_repo(n).await?;
+    serve_git(&tuple_repo_name(n), repo, &path, &query, req).await
+}
+
+// tuple_repo returns the path to the n-ary tuple repo, generating it if needed.
+async fn tuple_repo(n: u64) -> Result<std::path::PathBuf, anyhow::Error> {
+    if n > family::MAX_ARITY {
+        anyhow::bail!(
+            "tuples of more than {} elements aren't served",
+            family::MAX_ARITY
+        );
+    }
+    let latest = tuple::RELEASES.last().unwrap().version;
+    ensure_repo(&tuple_repo_name(n), latest, move |root| {
+        write_nary_tuple(root, n)
+    })
+    .await
+}
 
-    // and now handle the clone if request
+// tuple_repo_name is the path of the n-ary tuple repo, both on the site and under repos/.
+fn tuple_repo_name(n: u64) -> String {
+    format!("tuple/{}/tuple", n)
+}
+
+// serve_git responds to a git request for the named repo, at the path repo, counting each response
+// which carries a pack as a clone (see badge::count_clone).
+async fn serve_git(
+    name: &str,
+    repo: std::path::PathBuf,
+    path: &str,
+    query: &HashMap<String, String>,
+    mut req: axum::http::Request<axum::body::Body>,
+) -> Result<axum::http::Response<axum::body::Full<axum::body::Bytes>>, anyhow::Error> {
+    // handle the clone if request
     // Portions of this code are derived from palletizer-rs https://github.com/de-vri-es/palletizer-rs/tree/0fda76a8c398a266f65f517c47fc4a55ca3faaf7
     // Original code Copyright (c) 2020, Maarten de Vries, used under the terms of the BSD-2 license.
     // Modifications are under the AGPL, per this repo's license
-    match path.as_str() {
+    match path {
         "/info/refs" => {
             if query.get("service").unwrap_or(&"".to_string()) != &"git-upload-pack".to_string() {
                 anyhow::bail!("unsupported service; use a better git client");
             }
 
-            let child = std::process::Command::new("git-upload-pack")
+            let output = tokio::process::Command::new("git-upload-pack")
                 .arg("--advertise-refs")
                 .arg(repo)
                 .stdin(std::process::Stdio::null())
-                .stdout(std::process::Stdio::piped())
-                .stderr(std::process::Stdio::piped())
-                .spawn()
-                .unwrap();
-
-            let output = child.wait_with_output().unwrap();
+                .output()
+                .await
+                .context("could not run git-upload-pack")?;
 
             if !output.status.success() {
                 anyhow::bail!(
@@ -187,30 +905,37 @@ async fn tuple_n_git(
         }
         "/git-upload-pack" => {
             // TODO: check Content-type
-            let body_data = req.body_mut().data().await.unwrap();
-            let mut child = std::process::Command::new("git-upload-pack")
+            let body_data = req.body_mut().data().await.unwrap()?;
+            let mut child = tokio::process::Command::new("git-upload-pack")
                 .arg("--stateless-rpc")
                 .arg(repo)
                 .stdin(std::process::Stdio::piped())
                 .stdout(std::process::Stdio::piped())
                 .stderr(std::process::Stdio::inherit())
                 .spawn()
-                .unwrap();
+                .context("could not run git-upload-pack")?;
 
+            // write the request while reading the response, so that neither pipe fills up while
+            // the other waits
             let mut child_in = child.stdin.take().unwrap();
-            child_in.write_all(&body_data.unwrap())?;
-            child_in.flush()?;
-            std::mem::drop(child_in);
-
-            let mut resp_body = child.stdout.take().unwrap();
-            let mut resp = Vec::new();
-            resp_body.read_to_end(&mut resp)?;
-
-            let child_res = child.wait()?;
+            let request = async move {
+                use tokio::io::AsyncWriteExt;
+                child_in.write_all(&body_data).await
+                // dropping child_in closes it
+            };
+            let (written, output) = tokio::join!(request, child.wait_with_output());
+            let output = output?;
+            written?;
 
-            if !child_res.success() {
+            if !output.status.success() {
                 anyhow::bail!("git-upload-pack did not exit with success");
             }
+            let resp = output.stdout;
+            // a clone or fetch negotiates over as many requests as it needs, but only the last
+            // response carries the pack
+            if carries_pack(&resp) {
+                badge::count_clone(name);
+            }
 
             // done, return response
             axum::http::Response::builder()
@@ -224,12 +949,60 @@ async fn tuple_n_git(
     }
 }
 
-fn init_repo(n: u64) -> Result<std::path::PathBuf, anyhow::Error> {
-    let path: std::path::PathBuf = format!("repos/{}", n).into();
+// carries_pack is whether a git-upload-pack response contains a packfile, which starts with 'PACK'
+// and a version of 2 or 3, whether it's sent in a sideband or not.
+fn carries_pack(resp: &[u8]) -> bool {
+    resp.windows(8)
+        .any(|w| w == b"PACK\0\0\0\x02" || w == b"PACK\0\0\0\x03")
+}
+
+// ensure_repo is init_repo for async handlers: repos which don't exist yet are mirrored or
+// generated on the generation pool, rather than blocking the handler's thread, while existing repos
+// are returned without waiting behind them. Concurrent requests for the same new repo share one
+// mirroring or generation of it.
+async fn ensure_repo(
+    name: &str,
+    version: &'static str,
+    generate: impl Fn(&std::path::Path) -> Result<(), anyhow::Error> + Send + 'static,
+) -> Result<std::path::PathBuf, anyhow::Error> {
+    let path = repo_path(name, version);
+    if path.is_dir() {
+        return Ok(path);
+    }
+    let _flight = pool::flight(&path).await;
+    if path.is_dir() {
+        return Ok(path);
+    }
+    let fetched = match mirror::upstream() {
+        Some(upstream) => mirror::mirror(upstream, name.to_string()).await?,
+        None => None,
+    };
+    let name = name.to_string();
+    pool::pool()
+        .run(move || init_repo(&name, version, fetched, generate))
+        .await?
+}
+
+// repo_path is where the given version of the named repo is stored.
+fn repo_path(name: &str, version: &str) -> std::path::PathBuf {
+    format!("repos/{}@{}", name, version).into()
+}
+
+// init_repo returns the path to a generated repo, served at 'https://pkg.golang.fail/{name}.git',
+// creating it if it doesn't exist yet, from the repo fetched from the upstream if there is one (see
+// mirror::mirror), once generate checks it, or else by calling generate.
+// version should be the latest version of the repo, so that a new release regenerates it.
+fn init_repo(
+    name: &str,
+    version: &str,
+    fetched: Option<tempfile::TempDir>,
+    generate: impl Fn(&std::path::Path) -> Result<(), anyhow::Error>,
+) -> Result<std::path::PathBuf, anyhow::Error> {
+    let path = repo_path(name, version);
     match std::fs::read_dir(&path) {
         Ok(_) => return Ok(path),
         Err(e) if e.kind() == std::io::ErrorKind::NotFound => {
-            println!("not found: {}", n);
+            println!("not found: {}", name);
             // we should create it
         }
         r => {
@@ -237,10 +1010,27 @@ fn init_repo(n: u64) -> Result<std::path::PathBuf, anyhow::Error> {
         }
     };
 
-    std::fs::create_dir_all("repos")?;
-    // create it
-    let tmp = tempfile::TempDir::new_in("repos")?;
-    write_nary_tuple(tmp.path(), n)?;
+    std::fs::create_dir_all(path.parent().unwrap())?;
+    // other processes sharing repos/ may be creating it too, so take turns, and check again once
+    // it's our turn
+    let _lock = lock::exclusive(&lock::path(&path))?;
+    if path.is_dir() {
+        return Ok(path);
+    }
+    // create it, either from the upstream's copy or by generating it ourselves
+    let tmp = match fetched {
+        Some(tmp) => {
+            mirror::verify(tmp.path(), name, &generate)?;
+            println!("mirrored: {}", name);
+            tmp
+        }
+        None => {
+            println!("generating: {}", name);
+            let tmp = tempfile::TempDir::new_in("repos")?;
+            generate(tmp.path())?;
+            tmp
+        }
+    };
 
     // repo created; persist the tempfile into place atomically and away we go
     let tmp_path = tmp.into_path();
@@ -249,156 +1039,145 @@ fn init_repo(n: u64) -> Result<std::path::PathBuf, anyhow::Error> {
         Ok(_) => Ok(path),
         Err(e) if e.kind() == std::io::ErrorKind::AlreadyExists => {
             let _ = std::fs::remove_dir_all(&tmp_path);
-            // race, it's already there, so it's right
+            // race with something not taking the lock, it's already there, so it's right
             Ok(path)
         }
         Err(e) => Err(e)?,
     }
 }
 
-const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+// A RepoCommit is one commit of a generated repo, which gets tagged with its version.
+struct RepoCommit {
+    version: &'static str,
+    message: &'static str,
+    // fixed, so generated repos are reproducible
+    time: i64,
+    files: Vec<(&'static str, Vec<u8>)>,
+}
+
+// initial_commit is the commit of a module's first release.
+fn initial_commit(
+    version: &'static str,
+    time: i64,
+    files: Vec<(&'static str, Vec<u8>)>,
+) -> RepoCommit {
+    RepoCommit {
+        version,
+        message: "Initial commit",
+        time,
+        files,
+    }
+}
+
+// tuple_commits returns a commit for each release of the n-ary tuple, oldest first.
+fn tuple_commits(n: u64) -> impl Iterator<Item = RepoCommit> {
+    tuple::RELEASES.iter().map(move |release| RepoCommit {
+        version: release.version,
+        message: release.message,
+        time: release.time,
+        files: tuple::files(release, n),
+    })
+}
 
 fn write_nary_tuple(root: &std::path::Path, n: u64) -> Result<(), anyhow::Error> {
-    let mut f = std::fs::File::create(root.join("go.mod"))?;
-    f.write_all(
-        format!(
-            r#"module pkg.golang.fail/tuple/{}/tuple
+    write_repo(root, tuple_commits(n))
+}
 
-go 1.18"#,
-            n
-        )
-        .as_bytes(),
-    )?;
-    std::mem::drop(f);
+// write_repo makes a reproducible git repo at root, with the given commits on the main branch.
+fn write_repo(
+    root: &std::path::Path,
+    commits: impl IntoIterator<Item = RepoCommit>,
+) -> Result<(), anyhow::Error> {
+    let repo = git2::Repository::init(&root)?;
+    let mut index = repo.index()?;
+    let mut parent: Option<git2::Commit> = None;
 
-    let constraints = if n == 0 {
-        "".into()
-    } else {
-        format!(
-            "[{} any]",
-            (0..n)
-                .map(|i| format!("T{}", i))
-                .collect::<Vec<_>>()
-                .join(", ")
-        )
-    };
+    for c in commits {
+        index.clear()?;
+        for (name, contents) in c.files {
+            std::fs::File::create(root.join(name))?.write_all(&contents)?;
+            index.add_path(std::path::Path::new(name))?;
+        }
+        let tree_id = index.write_tree()?;
+        let tree = repo.find_tree(tree_id)?;
 
-    let tuple_members = if n == 0 {
-        "".into()
-    } else {
-        (0..n)
-            .map(|i| format!("T{i} T{i}"))
-            .collect::<Vec<_>>()
-            .join("\n\t")
-    };
+        let sig = git2::Signature::new(
+            "Euan Kemp",
+            &format!("{}{}{}", "euank", "@", "euank.com"),
+            &git2::Time::new(c.time, 69),
+        )?;
 
-    let inst_params = if n == 0 {
-        "".into()
-    } else {
-        format!(
-            "[{}]",
-            (0..n)
-                .map(|i| format!("T{}", i))
-                .collect::<Vec<_>>()
-                .join(", ")
-        )
-    };
+        let commit_id = repo.commit(
+            Some("refs/heads/main"),
+            &sig,
+            &sig,
+            c.message,
+            &tree,
+            &parent.iter().collect::<Vec<_>>(),
+        )?;
+        let commit = repo.find_commit(commit_id)?;
+        repo.tag_lightweight(c.version, commit.as_object(), false)?;
+        parent = Some(commit);
+    }
+    std::mem::drop(index);
 
-    let multiple_ret = if n == 0 {
-        "".into()
-    } else {
-        format!(
-            "({}) ",
-            (0..n)
-                .map(|i| format!("T{}", i))
-                .collect::<Vec<_>>()
-                .join(", ")
-        )
-    };
+    repo.set_head("refs/heads/main")?;
+    Ok(())
+}
 
-    let multiple_ret_body = if n == 0 {
-        "".into()
-    } else {
-        format!(
-            "return {}",
-            (0..n)
-                .map(|i| format!("t.T{}", i))
-                .collect::<Vec<_>>()
-                .join(", ")
-        )
-    };
+#[cfg(test)]
+mod tests {
+    use super::*;
 
-    let new_args = (0..n)
-        .map(|i| format!("t{i} T{i}"))
-        .collect::<Vec<_>>()
-        .join(", ");
-
-    let struct_inst_body = (0..n)
-        .map(|i| format!("t{}", i))
-        .collect::<Vec<_>>()
-        .join(", ");
-
-    // and now the impl
-    let mut f = std::fs::File::create(root.join("tuple.go"))?;
-    f.write_all(
-        format!(
-            r#"// Package tuple provides an {n}-ary tuple implementation. This is useful for cases
-// where multiple values should be grouped together, such as for passing across channels.
-// Multiple returns and n-ary tuples may be converted between as well by using the 'New' method to
-// go from n-ary return to a Tuple, and using Tuple.Unpack to convert in the other direction.
-package tuple
-
-// Tuple implements an {n}-ary generic tuple.
-// It may be used
-type Tuple{constraints} struct {{
-	{tuple_members}
-}}
-
-// New constructs a new tuple from the given arguments.
-func New{constraints}({new_args}) Tuple{inst_params} {{
-	return Tuple{inst_params}{{{struct_inst_body}}}
-}}
-
-// Unpack unpacks a Tuple via multiple-return of the contained data.
-func (t Tuple{inst_params}) Unpack() {multiple_ret}{{
-	{multiple_ret_body}
-}}
-"#
+    #[test]
+    fn write_repo_tags_major_versions() {
+        let dir = tempfile::TempDir::new().unwrap();
+        let go_mod = |path: &str| vec![("go.mod", format!("module {}\n", path).into_bytes())];
+        write_repo(
+            dir.path(),
+            [
+                initial_commit("v1.0.0", 420, go_mod("pkg.golang.fail/tuple/3/tuple")),
+                RepoCommit {
+                    version: "v2.0.0",
+                    message: "Break the API",
+                    time: 840,
+                    files: go_mod("pkg.golang.fail/tuple/3/tuple/v2"),
+                },
+            ],
         )
-        .as_bytes(),
-    )?;
-    std::mem::drop(f);
-    {
-        std::fs::File::create(root.join("LICENSE"))?.write_all(BSD3_LICENSE)?;
-    }
-
-    // all files ready, now try to make a reproducible git repo
+        .unwrap();
 
-    let repo = git2::Repository::init(&root)?;
+        for (tag, path) in [
+            ("v1.0.0", "pkg.golang.fail/tuple/3/tuple"),
+            ("v2.0.0", "pkg.golang.fail/tuple/3/tuple/v2"),
+            ("main", "pkg.golang.fail/tuple/3/tuple/v2"),
+            ("refs/tags/v1.0.0", "pkg.golang.fail/tuple/3/tuple"),
+            ("refs/heads/main", "pkg.golang.fail/tuple/3/tuple/v2"),
+        ] {
+            assert_eq!(
+                read_file(dir.path(), &format!("{}/go.mod", tag)).unwrap(),
+                Some((
+                    "go.mod".to_string(),
+                    format!("module {}\n", path).into_bytes()
+                )),
+                "{}",
+                tag
+            );
+        }
+        assert_eq!(read_file(dir.path(), "v3.0.0/go.mod").unwrap(), None);
+        assert_eq!(read_file(dir.path(), "v1.0.0/nope").unwrap(), None);
+    }
 
-    let mut tree = repo.index()?;
-    tree.add_path(std::path::Path::new("go.mod"))?;
-    tree.add_path(std::path::Path::new("tuple.go"))?;
-    tree.add_path(std::path::Path::new("LICENSE"))?;
-    let tree_id = tree.write_tree()?;
-    std::mem::drop(tree);
-
-    let tree = repo.find_tree(tree_id)?;
-
-    let sig = git2::Signature::new(
-        "Euan Kemp",
-        &format!("{}{}{}", "euank", "@", "euank.com"),
-        &git2::Time::new(420, 69),
-    )?;
-
-    repo.commit(
-        Some("refs/heads/main"),
-        &sig,
-        &sig,
-        "Initial commit",
-        &tree,
-        &[],
-    )?;
-    repo.set_head("refs/heads/main")?;
-    Ok(())
+    #[test]
+    fn counts_only_responses_carrying_packs() {
+        // a negotiation round, and the last round, with the pack in sideband 1
+        assert!(!carries_pack(b"0008NAK\n0000"));
+        assert!(carries_pack(
+            b"0008NAK\n0018\x01PACK\0\0\0\x02\0\0\0\x03..."
+        ));
+        // without a sideband
+        assert!(carries_pack(b"0008NAK\nPACK\0\0\0\x03\0\0\0\x03"));
+        // a ref advertisement, which happens to name a 'PACK' branch
+        assert!(!carries_pack(b"003f0123 refs/heads/PACK\0"));
+    }
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	// import a match for regexps with 3 capture groups
+	match3 "pkg.golang.fail/match/3/match"
+)
+
+// access log lines look like: 10.0.0.7 GET /healthz 200 1.5ms
+var access = match3.MustCompile[netip.Addr, int, time.Duration](
+	`(\S+) \S+ \S+ (\d+) (\S+)`,
+)
+
+func main() {
+	// each group is parsed into its element type: netip.Addr with UnmarshalText, int with
+	// strconv, and time.Duration with time.ParseDuration
+	t, ok, err := access.Match("10.0.0.7 GET /healthz 200 1.5ms")
+	fmt.Println(t, ok, err) // {10.0.0.7 200 1.5ms} true <nil>
+
+	addr, status, took := t.Unpack()
+	fmt.Println(addr.Is4(), status == 200, took < time.Second) // true true true
+
+	logs := "10.0.0.7 GET / 200 2ms\n10.0.0.8 GET /admin 403 7ms\n10.0.0.9 GET / 200 soon\n"
+	for t, err := range access.FindAll(logs) {
+		if err != nil {
+			fmt.Println(err) // match: group 3: time: invalid duration "soon"
+			continue
+		}
+		fmt.Println(t) // {10.0.0.7 200 2ms}, then {10.0.0.8 403 7ms}
+	}
+
+	// the number of groups is checked when compiling, as are their types
+	_, err = match3.Compile[string, string, string](`(\w+) (\w+)`)
+	fmt.Println(err) // match: "(\\w+) (\\w+)" has 2 capture groups, want 3
+	_, err = match3.Compile[string, string, chan int](`(\w+) (\w+) (\w+)`)
+	fmt.Println(err) // match: can't parse group 3 into chan int
+}
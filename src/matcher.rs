@@ -0,0 +1,240 @@
+// The regexp match module template: regexps with n capture groups, whose matches are parsed into
+// n-ary tuples of the requested types. (The module is named for its go package, 'match', which
+// is a keyword here.)
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1792051200;
+
+// the tuple release whose modules hold matches; pinned, since released match modules can never
+// change
+const TUPLE_VERSION: &str = "v1.2.0";
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/match/{}/match", n)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.23\n\nrequire {} {}\n",
+                module_path(n),
+                crate::tuple::module_path(n, 1),
+                TUPLE_VERSION
+            )
+            .into_bytes(),
+        ),
+        ("match.go", match_go(n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn list(n: u64, f: impl Fn(u64) -> String) -> String {
+    (0..n).map(f).collect::<Vec<_>>().join(", ")
+}
+
+fn match_go(n: u64) -> String {
+    let types = list(n, |i| format!("T{}", i));
+    // a regexp without capture groups isn't generic at all
+    let (decl, inst) = if n == 0 {
+        ("".to_string(), "".to_string())
+    } else {
+        (format!("[{} any]", types), format!("[{}]", types))
+    };
+    let tuple = format!("tuple.Tuple{}", inst);
+
+    let check_types = if n == 0 {
+        "".to_string()
+    } else {
+        format!(
+            "\tfor i, t := range []reflect.Type{{\n{}\t}} {{\n\t\tif !parsable(t) {{\n\t\t\treturn nil, fmt.Errorf(\"match: can't parse group %d into %s\", i+1, t)\n\t\t}}\n\t}}\n",
+            (0..n)
+                .map(|i| format!("\t\treflect.TypeFor[T{}](),\n", i))
+                .collect::<String>()
+        )
+    };
+    let parse_groups = (0..n)
+        .map(|i| {
+            format!(
+                "\tvar v{i} T{i}\n\tif err := group(&v{i}, s, m, {group}); err != nil {{\n\t\treturn {tuple}{{}}, err\n\t}}\n",
+                i = i,
+                group = i + 1,
+                tuple = tuple
+            )
+        })
+        .collect::<String>();
+    let values = list(n, |i| format!("v{}", i));
+    let groups = if n == 1 { "group" } else { "groups" };
+
+    format!(
+        r#"// Package match parses the capture groups of regexp matches into tuples from
+// pkg.golang.fail/tuple/{n}/tuple, for regexps with {n} capture {groups}.
+//
+// Each group is parsed according to its element type: strings and byte slices are taken as they
+// are, bools and numbers are parsed with strconv, time.Durations with time.ParseDuration, and
+// types whose pointers implement encoding.TextUnmarshaler with their UnmarshalText method.
+// Named types are parsed according to their underlying type, unless they implement
+// encoding.TextUnmarshaler. Groups which don't take part in a match, such as '(\d+)?' matching
+// nothing, are left as zero values.
+package match
+
+import (
+	"encoding"
+	"fmt"
+	"iter"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"{tuple_path}"
+)
+
+// Regexp is a compiled regexp with {n} capture {groups}. Regexps are safe for concurrent use.
+type Regexp{decl} struct {{
+	re *regexp.Regexp
+}}
+
+// Compile parses a regexp, which must have exactly {n} capture {groups}, each of which must be
+// parsable into the corresponding element type.
+func Compile{decl}(pattern string) (*Regexp{inst}, error) {{
+	re, err := regexp.Compile(pattern)
+	if err != nil {{
+		return nil, err
+	}}
+	if got := re.NumSubexp(); got != {n} {{
+		return nil, fmt.Errorf("match: %q has %d capture groups, want {n}", pattern, got)
+	}}
+{check_types}	return &Regexp{inst}{{re: re}}, nil
+}}
+
+// MustCompile is like Compile, but panics if the regexp can't be compiled.
+func MustCompile{decl}(pattern string) *Regexp{inst} {{
+	r, err := Compile{inst}(pattern)
+	if err != nil {{
+		panic(err)
+	}}
+	return r
+}}
+
+// Regexp returns the underlying regexp.
+func (r *Regexp{inst}) Regexp() *regexp.Regexp {{
+	return r.re
+}}
+
+// Match reports whether s contains a match of the regexp, and if so, returns the leftmost match
+// parsed into a tuple, along with any error parsing it.
+func (r *Regexp{inst}) Match(s string) ({tuple}, bool, error) {{
+	m := r.re.FindStringSubmatchIndex(s)
+	if m == nil {{
+		return {tuple}{{}}, false, nil
+	}}
+	t, err := r.parse(s, m)
+	return t, true, err
+}}
+
+// FindAll returns an iterator over every successive non-overlapping match in s, parsed into a
+// tuple, along with any error parsing it.
+func (r *Regexp{inst}) FindAll(s string) iter.Seq2[{tuple}, error] {{
+	return func(yield func({tuple}, error) bool) {{
+		for _, m := range r.re.FindAllStringSubmatchIndex(s, -1) {{
+			if !yield(r.parse(s, m)) {{
+				return
+			}}
+		}}
+	}}
+}}
+
+func (r *Regexp{inst}) parse(s string, m []int) ({tuple}, error) {{
+{parse_groups}	return tuple.New({values}), nil
+}}
+
+var (
+	textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+	duration        = reflect.TypeFor[time.Duration]()
+)
+
+// parsable reports whether parse supports values of type t.
+func parsable(t reflect.Type) bool {{
+	if reflect.PointerTo(t).Implements(textUnmarshaler) || t == duration {{
+		return true
+	}}
+	switch t.Kind() {{
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}}
+	return false
+}}
+
+// group parses group i of the match m in s into *p, leaving it as it is if the group didn't
+// take part in the match.
+func group(p any, s string, m []int, i int) error {{
+	start, end := m[2*i], m[2*i+1]
+	if start < 0 {{
+		return nil
+	}}
+	if err := parse(p, s[start:end]); err != nil {{
+		return fmt.Errorf("match: group %d: %w", i, err)
+	}}
+	return nil
+}}
+
+func parse(p any, s string) error {{
+	if u, ok := p.(encoding.TextUnmarshaler); ok {{
+		return u.UnmarshalText([]byte(s))
+	}}
+	if d, ok := p.(*time.Duration); ok {{
+		v, err := time.ParseDuration(s)
+		*d = v
+		return err
+	}}
+
+	v := reflect.ValueOf(p).Elem()
+	switch v.Kind() {{
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {{
+			return err
+		}}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {{
+			return err
+		}}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {{
+			return err
+		}}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {{
+			return err
+		}}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	default:
+		// Compile checks every type is parsable
+		panic("match: unparsable type " + v.Type().String())
+	}}
+	return nil
+}}
+"#,
+        tuple_path = crate::tuple::module_path(n, 1),
+    )
+}
@@ -0,0 +1,336 @@
+// The r×c matrix module template.
+//
+// Each size of matrix is its own module, so a module can't refer to the modules of every size it
+// might be multiplied with, and an r×c module importing c×r for Transpose (and vice versa) would
+// be an import cycle. Instead, Mul and Transpose are functions in the module of their result, and
+// accept their arguments via interfaces of rows and columns; go (1.21 and later) infers the
+// vector types, which checks that the shared dimension of a product matches at compile time.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791957600;
+
+pub fn module_path(r: u64, c: u64) -> String {
+    format!("pkg.golang.fail/matrix/{}/{}", r, c)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(r: u64, c: u64) -> Vec<(&'static str, Vec<u8>)> {
+    let mut requires = vec![(crate::vector::module_path(c), crate::vector::VERSION)];
+    if r != c {
+        requires.push((crate::vector::module_path(r), crate::vector::VERSION));
+    }
+    // matrices convert to and from tuples of their rows
+    requires.push((
+        crate::tuple::module_path(r, 1),
+        crate::vector::TUPLE_VERSION,
+    ));
+    requires.sort();
+    let requires = requires
+        .iter()
+        .map(|(path, version)| format!("\t{} {}\n", path, version))
+        .collect::<String>();
+
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.21\n\nrequire (\n{})\n",
+                module_path(r, c),
+                requires
+            )
+            .into_bytes(),
+        ),
+        ("matrix.go", matrix_go(r, c).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn matrix_go(r: u64, c: u64) -> String {
+    // rows are c-vectors, and columns are r-vectors
+    let (mut imports, rowvec, colvec) = if r == c {
+        (
+            vec![("", crate::vector::module_path(c))],
+            "vector",
+            "vector",
+        )
+    } else {
+        (
+            vec![
+                ("colvec ", crate::vector::module_path(r)),
+                ("rowvec ", crate::vector::module_path(c)),
+            ],
+            "rowvec",
+            "colvec",
+        )
+    };
+    imports.push(("", crate::tuple::module_path(r, 1)));
+    // sorted by path, as gofmt would
+    imports.sort_by(|a, b| a.1.cmp(&b.1));
+    let imports = imports
+        .iter()
+        .map(|(name, path)| format!("\t{}\"{}\"\n", name, path))
+        .collect::<String>();
+
+    // a tuple of the rows
+    let tuple_params = (0..r)
+        .map(|_| format!("{}.Vec[T]", rowvec))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let tuple_elems = (0..r)
+        .map(|i| format!("t.T{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let rows = (0..r)
+        .map(|i| format!("m.Row({})", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+
+    let identity = if r == c {
+        format!(
+            r#"
+// Identity returns the {r}×{c} identity matrix.
+func Identity[T Number]() Mat[T] {{
+	var m Mat[T]
+	for i := range m {{
+		m[i][i] = 1
+	}}
+	return m
+}}
+"#
+        )
+    } else {
+        "".into()
+    };
+
+    format!(
+        r#"// Package matrix provides a {r}×{c} matrix type.
+//
+// Matrices of other sizes are in their own modules, such as pkg.golang.fail/matrix/{c}/{r} for the
+// transpose of this one. Mul and Transpose are functions in the module of the matrix they return,
+// and take matrices of any size that fits.
+//
+// Rows and columns are vectors from pkg.golang.fail/vector, which convert to and from tuples, as
+// matrices do to and from tuples of their rows.
+package matrix
+
+import (
+{imports})
+
+// Number is the set of types matrices may contain.
+type Number = {rowvec}.Number
+
+// Mat is a {r}×{c} matrix, indexed by row and then column.
+type Mat[T Number] [{r}][{c}]T
+{identity}
+// FromRows constructs a matrix from its rows.
+func FromRows[T Number](rows [{r}]{rowvec}.Vec[T]) Mat[T] {{
+	var m Mat[T]
+	for i, row := range rows {{
+		m[i] = row
+	}}
+	return m
+}}
+
+// FromCols constructs a matrix from its columns.
+func FromCols[T Number](cols [{c}]{colvec}.Vec[T]) Mat[T] {{
+	var m Mat[T]
+	for j, col := range cols {{
+		for i, v := range col {{
+			m[i][j] = v
+		}}
+	}}
+	return m
+}}
+
+// FromTuple constructs a matrix from a tuple of its {r} rows.
+func FromTuple[T Number](t tuple.Tuple[{tuple_params}]) Mat[T] {{
+	return Mat[T]{{{tuple_elems}}}
+}}
+
+// Tuple converts the matrix to a tuple of its {r} rows.
+func (m Mat[T]) Tuple() tuple.Tuple[{tuple_params}] {{
+	return tuple.New({rows})
+}}
+
+// Row returns row i of the matrix.
+func (m Mat[T]) Row(i int) {rowvec}.Vec[T] {{
+	return m[i]
+}}
+
+// Col returns column j of the matrix.
+func (m Mat[T]) Col(j int) {colvec}.Vec[T] {{
+	var col {colvec}.Vec[T]
+	for i := range m {{
+		col[i] = m[i][j]
+	}}
+	return col
+}}
+
+// Rows returns every row of the matrix.
+func (m Mat[T]) Rows() [{r}]{rowvec}.Vec[T] {{
+	var rows [{r}]{rowvec}.Vec[T]
+	for i := range m {{
+		rows[i] = m[i]
+	}}
+	return rows
+}}
+
+// Cols returns every column of the matrix.
+func (m Mat[T]) Cols() [{c}]{colvec}.Vec[T] {{
+	var cols [{c}]{colvec}.Vec[T]
+	for i := range m {{
+		for j := range m[i] {{
+			cols[j][i] = m[i][j]
+		}}
+	}}
+	return cols
+}}
+
+// Add returns the sum of m and o.
+func (m Mat[T]) Add(o Mat[T]) Mat[T] {{
+	for i := range m {{
+		for j := range m[i] {{
+			m[i][j] += o[i][j]
+		}}
+	}}
+	return m
+}}
+
+// Sub returns the difference of m and o.
+func (m Mat[T]) Sub(o Mat[T]) Mat[T] {{
+	for i := range m {{
+		for j := range m[i] {{
+			m[i][j] -= o[i][j]
+		}}
+	}}
+	return m
+}}
+
+// Scale returns m multiplied by k.
+func (m Mat[T]) Scale(k T) Mat[T] {{
+	for i := range m {{
+		for j := range m[i] {{
+			m[i][j] *= k
+		}}
+	}}
+	return m
+}}
+
+// MulVec returns the product of m and the column vector v.
+func (m Mat[T]) MulVec(v {rowvec}.Vec[T]) {colvec}.Vec[T] {{
+	var out {colvec}.Vec[T]
+	for i := range m {{
+		out[i] = {rowvec}.Vec[T](m[i]).Dot(v)
+	}}
+	return out
+}}
+
+// Mul returns the matrix product of a, an {r}×N matrix, and b, an N×{c} matrix, for any N, such as
+// matrices from pkg.golang.fail/matrix/{r}/N and pkg.golang.fail/matrix/N/{c}.
+// a's rows and b's columns must be the same type of vector, so N is checked at compile time.
+func Mul[T Number, V interface{{ Dot(V) T }}](a interface{{ Rows() [{r}]V }}, b interface{{ Cols() [{c}]V }}) Mat[T] {{
+	var m Mat[T]
+	rows, cols := a.Rows(), b.Cols()
+	for i := range rows {{
+		for j := range cols {{
+			m[i][j] = rows[i].Dot(cols[j])
+		}}
+	}}
+	return m
+}}
+
+// Transpose returns the transpose of a {c}×{r} matrix, such as one from
+// pkg.golang.fail/matrix/{c}/{r}.
+func Transpose[T Number](m interface{{ Cols() [{r}]{rowvec}.Vec[T] }}) Mat[T] {{
+	return FromRows(m.Cols())
+}}
+"#
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use std::fs;
+    use std::path::Path;
+    use std::process::Command;
+
+    fn write(dir: &Path, files: Vec<(&'static str, Vec<u8>)>) {
+        fs::create_dir_all(dir).unwrap();
+        for (name, contents) in files {
+            fs::write(dir.join(name), contents).unwrap();
+        }
+    }
+
+    // Mul and Transpose only compile because go (1.21 and later) infers their vector types from
+    // the arguments' methods, so build and run the example from the matrix page against generated
+    // modules, with go itself.
+    #[test]
+    fn example_builds_and_runs() {
+        if Command::new("go").arg("version").output().is_err() {
+            eprintln!("go isn't installed, skipping");
+            return;
+        }
+        let tuple_release = crate::tuple::RELEASES
+            .iter()
+            .find(|release| release.version == crate::vector::TUPLE_VERSION)
+            .unwrap();
+
+        let dir = tempfile::TempDir::new().unwrap();
+        let mut modules = vec![];
+        for (r, c) in [(2, 2), (2, 3), (3, 2)] {
+            let path = dir.path().join(format!("matrix-{}-{}", r, c));
+            write(&path, super::files(r, c));
+            modules.push((super::module_path(r, c), super::VERSION, path));
+        }
+        for n in [2, 3] {
+            let path = dir.path().join(format!("vector-{}", n));
+            write(&path, crate::vector::files(n));
+            modules.push((crate::vector::module_path(n), crate::vector::VERSION, path));
+            let path = dir.path().join(format!("tuple-{}", n));
+            write(&path, crate::tuple::files(tuple_release, n));
+            modules.push((tuple_release.module_path(n), tuple_release.version, path));
+        }
+
+        let requires = modules
+            .iter()
+            .map(|(path, version, _)| format!("\t{} {}\n", path, version))
+            .collect::<String>();
+        let replaces = modules
+            .iter()
+            .map(|(path, _, dir)| format!("\t{} => {}\n", path, dir.display()))
+            .collect::<String>();
+        let main = dir.path().join("example");
+        write(
+            &main,
+            vec![
+                (
+                    "go.mod",
+                    format!(
+                        "module example\n\ngo 1.21\n\nrequire (\n{})\n\nreplace (\n{})\n",
+                        requires, replaces
+                    )
+                    .into_bytes(),
+                ),
+                ("main.go", include_bytes!("./matrix_example.go").to_vec()),
+            ],
+        );
+
+        let go = |args: &[&str]| {
+            let output = Command::new("go")
+                .args(args)
+                .current_dir(&main)
+                .env("GOFLAGS", "-mod=mod")
+                .env("GOPROXY", "off")
+                .env("GOWORK", "off")
+                .output()
+                .unwrap();
+            let stderr = String::from_utf8_lossy(&output.stderr);
+            assert!(output.status.success(), "go {:?}: {}", args, stderr);
+            String::from_utf8(output.stdout).unwrap()
+        };
+        go(&["vet", "."]);
+        assert_eq!(go(&["run", "."]), "[[14 32] [32 77]]\n14 32\n");
+    }
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	// import each size of matrix needed
+	matrix22 "pkg.golang.fail/matrix/2/2"
+	matrix23 "pkg.golang.fail/matrix/2/3"
+	matrix32 "pkg.golang.fail/matrix/3/2"
+)
+
+func main() {
+	a := matrix23.Mat[float64]{
+		{1, 2, 3},
+		{4, 5, 6},
+	}
+
+	// the transpose of a 2x3 matrix is 3x2, so Transpose comes from the 3x2 module
+	at := matrix32.Transpose(a)
+
+	// and the product of a 2x3 and a 3x2 matrix is 2x2
+	p := matrix22.Mul(a, at)
+	fmt.Println(p) // [[14 32] [32 77]]
+
+	// multiplying matrices with mismatched sizes doesn't compile:
+	// matrix22.Mul(a, a)
+
+	// rows and columns are vectors, which convert to tuples
+	x, y := p.Row(0).Tuple().Unpack()
+	fmt.Println(x, y) // 14 32
+}
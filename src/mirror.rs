@@ -0,0 +1,137 @@
+// Pull-through mirroring of generated repos from an upstream instance of this site.
+//
+// When the UPSTREAM environment variable is set (e.g. to 'https://pkg.golang.fail'), repos are
+// fetched from it rather than generated locally, so that the mirror serves byte-identical git
+// objects. Fetched repos are checked against our own generator output before they're cached, and
+// we only fall back to generating repos ourselves if the upstream can't be reached: any other
+// failure, such as the upstream responding with an error, fails the request.
+//
+// Only repos are mirrored. Everything else served about a module, such as raw files and the API,
+// is read from its repo or computed from the same templates, so it's identical too.
+
+use std::path::Path;
+use std::time::Duration;
+
+// how long to wait for a connection to the upstream before generating repos ourselves
+const CONNECT_TIMEOUT: Duration = Duration::from_secs(5);
+
+pub fn upstream() -> Option<String> {
+    std::env::var("UPSTREAM")
+        .ok()
+        .map(|u| u.trim_end_matches('/').to_string())
+        .filter(|u| !u.is_empty())
+}
+
+// mirror fetches the named repo from the upstream into a new directory under repos/, returning
+// None if the upstream can't be reached.
+// Fetching blocks, so it's done on a blocking thread, but not on the generation pool, since it's
+// mostly waiting on the upstream, and the pool's turns are for generating.
+pub async fn mirror(
+    upstream: String,
+    name: String,
+) -> Result<Option<tempfile::TempDir>, anyhow::Error> {
+    // libgit2 doesn't time out connecting, so check the upstream answers first
+    if let Err(e) = connect(&upstream).await {
+        println!("upstream unreachable, generating {} locally: {}", name, e);
+        return Ok(None);
+    }
+    tokio::task::spawn_blocking(move || {
+        std::fs::create_dir_all("repos")?;
+        let tmp = tempfile::TempDir::new_in("repos")?;
+        match fetch(&upstream, tmp.path(), &name) {
+            Ok(()) => Ok(Some(tmp)),
+            // the connection failed partway, rather than the upstream failing the request
+            Err(e) if e.class() == git2::ErrorClass::Net => {
+                println!("upstream unreachable, generating {} locally: {}", name, e);
+                Ok(None)
+            }
+            Err(e) => Err(anyhow::anyhow!(
+                "could not mirror {} from {}: {}",
+                name,
+                upstream,
+                e
+            )),
+        }
+    })
+    .await?
+}
+
+// connect checks that a TCP connection can be made to the upstream's host.
+async fn connect(upstream: &str) -> Result<(), anyhow::Error> {
+    let (scheme, rest) = upstream.split_once("://").ok_or_else(|| {
+        anyhow::anyhow!("UPSTREAM must be a URL, such as https://pkg.golang.fail")
+    })?;
+    let port = match scheme {
+        "http" => 80,
+        "https" => 443,
+        _ => anyhow::bail!("unsupported UPSTREAM scheme {:?}", scheme),
+    };
+    let host = rest.split('/').next().unwrap_or(rest);
+    let host = host.rsplit('@').next().unwrap_or(host);
+    let addr = match host.rsplit_once(':') {
+        Some((_, p)) if p.parse::<u16>().is_ok() => host.to_string(),
+        _ => format!("{}:{}", host, port),
+    };
+    tokio::time::timeout(CONNECT_TIMEOUT, tokio::net::TcpStream::connect(&addr))
+        .await
+        .map_err(|_| anyhow::anyhow!("timed out connecting to {}", addr))??;
+    Ok(())
+}
+
+// fetch mirrors every branch and tag of the named repo (see init_repo) from the upstream into a
+// new bare repo at root.
+pub fn fetch(upstream: &str, root: &Path, name: &str) -> Result<(), git2::Error> {
+    let repo = git2::Repository::init_bare(root)?;
+    let mut remote = repo.remote_anonymous(&format!("{}/{}.git", upstream, name))?;
+    remote.fetch(
+        &["+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"],
+        None,
+        None,
+    )?;
+    repo.set_head("refs/heads/main")?;
+    Ok(())
+}
+
+// verify checks that the fetched repo at root has exactly the same branches and tags, pointing at
+// exactly the same commits, as the repo generate creates.
+// Commit ids cover the whole tree and history, so this means every object is identical too.
+pub fn verify(
+    root: &Path,
+    name: &str,
+    generate: impl Fn(&Path) -> Result<(), anyhow::Error>,
+) -> Result<(), anyhow::Error> {
+    let local = tempfile::TempDir::new_in("repos")?;
+    generate(local.path())?;
+
+    let want = refs(&git2::Repository::open(local.path())?)?;
+    let got = refs(&git2::Repository::open(root)?)?;
+    if want != got {
+        anyhow::bail!(
+            "upstream repo for {} does not match local generator output: expected {:?}, got {:?}",
+            name,
+            want,
+            got
+        );
+    }
+    Ok(())
+}
+
+// refs returns the sorted names and targets of every branch and tag in a repo.
+pub fn refs(repo: &git2::Repository) -> Result<Vec<(String, git2::Oid)>, anyhow::Error> {
+    let mut refs = Vec::new();
+    for r in repo.references()? {
+        let r = r?;
+        let name = match r.name() {
+            Some(name) if name.starts_with("refs/heads/") || name.starts_with("refs/tags/") => {
+                name.to_string()
+            }
+            _ => continue,
+        };
+        let target = r
+            .target()
+            .ok_or_else(|| anyhow::anyhow!("{} is not a direct reference", name))?;
+        refs.push((name, target));
+    }
+    refs.sort();
+    Ok(refs)
+}
@@ -0,0 +1,221 @@
+// The function mock module template: spies for functions with `in` arguments and `out` results,
+// which record each call's arguments as a tuple.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791986400;
+
+// the tuple release whose modules hold arguments and results; pinned, since released mock modules
+// can never change
+const TUPLE_VERSION: &str = "v1.1.0";
+
+pub fn module_path(inn: u64, out: u64) -> String {
+    format!("pkg.golang.fail/mock/{}/{}", inn, out)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(inn: u64, out: u64) -> Vec<(&'static str, Vec<u8>)> {
+    let mut requires = vec![crate::tuple::module_path(inn, 1)];
+    if inn != out {
+        requires.push(crate::tuple::module_path(out, 1));
+    }
+    requires.sort();
+    let requires = requires
+        .iter()
+        .map(|path| format!("\t{} {}\n", path, TUPLE_VERSION))
+        .collect::<String>();
+
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.18\n\nrequire (\n{})\n",
+                module_path(inn, out),
+                requires
+            )
+            .into_bytes(),
+        ),
+        ("mock.go", mock_go(inn, out).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn list(n: u64, f: impl Fn(u64) -> String) -> String {
+    (0..n).map(f).collect::<Vec<_>>().join(", ")
+}
+
+fn mock_go(inn: u64, out: u64) -> String {
+    let (imports, args, results) = if inn == out {
+        (
+            format!(
+                "import (\n\t\"sync\"\n\n\t\"{}\"\n)",
+                crate::tuple::module_path(inn, 1)
+            ),
+            "tuple",
+            "tuple",
+        )
+    } else {
+        let mut imports = vec![
+            ("args", crate::tuple::module_path(inn, 1)),
+            ("results", crate::tuple::module_path(out, 1)),
+        ];
+        // sorted by path, as gofmt would
+        imports.sort_by(|a, b| a.1.cmp(&b.1));
+        (
+            format!(
+                "import (\n\t\"sync\"\n\n{})",
+                imports
+                    .iter()
+                    .map(|(name, path)| format!("\t{} \"{}\"\n", name, path))
+                    .collect::<String>()
+            ),
+            "args",
+            "results",
+        )
+    };
+
+    let arg_types = list(inn, |i| format!("A{}", i));
+    let result_types = list(out, |i| format!("R{}", i));
+    let all_types = [arg_types.as_str(), result_types.as_str()]
+        .iter()
+        .filter(|s| !s.is_empty())
+        .cloned()
+        .collect::<Vec<_>>()
+        .join(", ");
+    // a mock of func() isn't generic at all
+    let (decl, inst) = if all_types.is_empty() {
+        ("".to_string(), "".to_string())
+    } else {
+        (format!("[{} any]", all_types), format!("[{}]", all_types))
+    };
+    let inst_of = |types: &str| {
+        if types.is_empty() {
+            "".to_string()
+        } else {
+            format!("[{}]", types)
+        }
+    };
+    let (args_inst, results_inst) = (inst_of(&arg_types), inst_of(&result_types));
+
+    let func_results = match out {
+        0 => "".to_string(),
+        1 => " R0".to_string(),
+        _ => format!(" ({})", result_types),
+    };
+    let func_type = format!("func({}){}", arg_types, func_results);
+    let params = list(inn, |i| format!("a{} A{}", i, i));
+    let arg_values = list(inn, |i| format!("a{}", i));
+
+    // a function without results can't return the result of a call, so call and then return
+    let (return_canned, call_f) = if out == 0 {
+        (
+            "canned.Unpack()\n\t\treturn".to_string(),
+            format!("f({})", arg_values),
+        )
+    } else {
+        (
+            "return canned.Unpack()".to_string(),
+            format!("return f({})", arg_values),
+        )
+    };
+
+    let arguments = if inn == 1 { "argument" } else { "arguments" };
+    let results_word = if out == 1 { "result" } else { "results" };
+
+    format!(
+        r#"// Package mock provides spies for functions taking {inn} {arguments} and returning {out} {results_word}.
+//
+// A Mock records the arguments of each call as a tuple from pkg.golang.fail/tuple/{inn}/tuple,
+// and returns either canned results, as tuples from pkg.golang.fail/tuple/{out}/tuple, or the
+// results of the function it wraps.
+package mock
+
+{imports}
+
+// T is the subset of testing.TB which assertions report failures to.
+type T interface {{
+	Helper()
+	Errorf(format string, a ...any)
+}}
+
+// Mock records calls to its Func method, which has the signature of the function being mocked.
+// Mocks are safe for concurrent use.
+type Mock{decl} struct {{
+	mu      sync.Mutex
+	f       {func_type}
+	calls   []{args}.Tuple{args_inst}
+	returns []{results}.Tuple{results_inst}
+}}
+
+// Spy returns a mock whose Func calls f, once any canned results have been used up. If f is nil,
+// Func returns zero values instead.
+func Spy{decl}(f {func_type}) *Mock{inst} {{
+	return &Mock{inst}{{f: f}}
+}}
+
+// Func records a call with the given arguments, and then returns the next canned results, if any
+// are left, or otherwise calls the spied-on function. m.Func may be used anywhere the spied-on
+// function would be. The spied-on function is called without any locks held, so it may use the
+// mock itself.
+func (m *Mock{inst}) Func({params}){func_results} {{
+	m.mu.Lock()
+	m.calls = append(m.calls, {args}.New({arg_values}))
+	canned, ok := {results}.Tuple{results_inst}{{}}, false
+	if len(m.returns) > 0 {{
+		canned, ok = m.returns[0], true
+		m.returns = m.returns[1:]
+	}}
+	f := m.f
+	m.mu.Unlock()
+
+	if ok || f == nil {{
+		// with no canned results left, canned is the zero tuple
+		{return_canned}
+	}}
+	{call_f}
+}}
+
+// Return queues canned results, which the following calls to Func return, in order, instead of
+// calling the spied-on function.
+func (m *Mock{inst}) Return(canned ...{results}.Tuple{results_inst}) {{
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.returns = append(m.returns, canned...)
+}}
+
+// Calls returns the arguments of every call so far, in the order they were made.
+func (m *Mock{inst}) Calls() []{args}.Tuple{args_inst} {{
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]{args}.Tuple{args_inst}(nil), m.calls...)
+}}
+
+// CallCount returns the number of calls so far.
+func (m *Mock{inst}) CallCount() int {{
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.calls)
+}}
+
+// AssertCalled reports a failure to t unless there have been exactly n calls so far, and returns
+// whether there have been.
+func (m *Mock{inst}) AssertCalled(t T, n int) bool {{
+	if got := m.CallCount(); got != n {{
+		t.Helper()
+		t.Errorf("mock: called %d times, want %d", got, n)
+		return false
+	}}
+	return true
+}}
+
+// Reset forgets every call so far, along with any canned results which haven't been used.
+func (m *Mock{inst}) Reset() {{
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.returns = nil
+}}
+"#
+    )
+}
@@ -0,0 +1,43 @@
+package greeter
+
+import (
+	"errors"
+	"testing"
+
+	// mock functions taking 1 argument and returning 2 results
+	mock12 "pkg.golang.fail/mock/1/2"
+	"pkg.golang.fail/tuple/2/tuple"
+)
+
+type greeter struct {
+	lookup func(id int) (string, error)
+}
+
+func (g greeter) greet(id int) string {
+	name, err := g.lookup(id)
+	if err != nil {
+		return "hello, stranger"
+	}
+	return "hello, " + name
+}
+
+func TestGreet(t *testing.T) {
+	// spy on a real lookup function...
+	m := mock12.Spy(func(id int) (string, error) { return "gopher", nil })
+	// ...but make the first call fail
+	m.Return(tuple.New("", errors.New("no such user")))
+
+	g := greeter{lookup: m.Func}
+	if got := g.greet(1); got != "hello, stranger" {
+		t.Errorf("got %q", got)
+	}
+	if got := g.greet(2); got != "hello, gopher" {
+		t.Errorf("got %q", got)
+	}
+
+	// every call's arguments are recorded as a tuple
+	m.AssertCalled(t, 2)
+	if id := m.Calls()[1].T0; id != 2 {
+		t.Errorf("second lookup was for %d", id)
+	}
+}
@@ -0,0 +1,344 @@
+// The parser combinator module templates: a core module, 'pkg.golang.fail/parse/parser', with the
+// Parser type and the combinators which don't depend on arity, and for each n, a module with Seq,
+// which sequences n parsers into a parser of an n-ary tuple.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1792058400;
+
+pub const PARSER_VERSION: &str = "v1.0.0";
+pub const PARSER_TIME: i64 = 1792058400;
+
+// the tuple release whose modules hold sequences; pinned, since released parse modules can never
+// change
+const TUPLE_VERSION: &str = "v1.1.0";
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/parse/{}/parse", n)
+}
+
+pub const PARSER_MODULE_PATH: &str = "pkg.golang.fail/parse/parser";
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn parser_files() -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.18\n", PARSER_MODULE_PATH).into_bytes(),
+        ),
+        ("parser.go", PARSER_GO.as_bytes().to_vec()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.18\n\nrequire (\n\t{} {}\n\t{} {}\n)\n",
+                module_path(n),
+                PARSER_MODULE_PATH,
+                PARSER_VERSION,
+                crate::tuple::module_path(n, 1),
+                TUPLE_VERSION
+            )
+            .into_bytes(),
+        ),
+        ("parse.go", parse_go(n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn list(n: u64, f: impl Fn(u64) -> String) -> String {
+    (0..n).map(f).collect::<Vec<_>>().join(", ")
+}
+
+fn parse_go(n: u64) -> String {
+    let types = list(n, |i| format!("T{}", i));
+    // a sequence of no parsers isn't generic at all
+    let (decl, tuple) = if n == 0 {
+        ("".to_string(), "tuple.Tuple".to_string())
+    } else {
+        (
+            format!("[{} any]", types),
+            format!("tuple.Tuple[{}]", types),
+        )
+    };
+    let params = list(n, |i| format!("p{} parser.Parser[T{}]", i, i));
+    let body = if n == 0 {
+        "\t\treturn tuple.New(), start, nil\n".to_string()
+    } else {
+        let steps = (0..n)
+            .map(|i| {
+                format!(
+                    "\t\tv{i}, in, err := p{i}(in)\n\t\tif err != nil {{\n\t\t\treturn {tuple}{{}}, start, err\n\t\t}}\n",
+                    i = i,
+                    tuple = tuple
+                )
+            })
+            .collect::<String>();
+        format!(
+            "\t\tin := start\n{}\t\treturn tuple.New({}), in, nil\n",
+            steps,
+            list(n, |i| format!("v{}", i))
+        )
+    };
+    let parsers = if n == 1 { "parser" } else { "parsers" };
+
+    format!(
+        r#"// Package parse provides Seq, which sequences {n} {parsers} from {parser_path} into a parser of
+// a tuple from {tuple_path}.
+package parse
+
+import (
+	"{parser_path}"
+	"{tuple_path}"
+)
+
+// Seq returns a parser which parses with each of the given parsers in turn, each starting where
+// the last left off, and returns their results as a tuple. If any of them fails, Seq fails with
+// its error.
+func Seq{decl}({params}) parser.Parser[{tuple}] {{
+	return func(start parser.Input) ({tuple}, parser.Input, error) {{
+{body}	}}
+}}
+"#,
+        parser_path = PARSER_MODULE_PATH,
+        tuple_path = crate::tuple::module_path(n, 1),
+    )
+}
+
+const PARSER_GO: &str = r#"// Package parser provides parser combinators: parsers for small pieces of text, and functions
+// which combine them into parsers for larger ones.
+//
+// Sequencing parsers of different types needs a function for each number of parsers, so Seq is
+// in the pkg.golang.fail/parse/$NUM/parse modules instead, where $NUM is the number of parsers.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Parser parses a T from the start of its input, and returns it along with the rest of the
+// input. If it fails, it returns an error, which is an *Error unless the parser has some other
+// reason to stop parsing altogether, along with its input unchanged.
+type Parser[T any] func(in Input) (T, Input, error)
+
+// Input is what's left of a string being parsed.
+type Input struct {
+	src string
+	off int
+	// shared by every input of the same string
+	state *state
+}
+
+type state struct {
+	// the failure furthest into the string so far
+	furthest *Error
+}
+
+// NewInput returns the input for parsing all of s.
+func NewInput(s string) Input {
+	return Input{src: s, state: &state{}}
+}
+
+// Rest returns what's left of the string.
+func (in Input) Rest() string {
+	return in.src[in.off:]
+}
+
+// Advance returns the input left after the next n bytes.
+func (in Input) Advance(n int) Input {
+	in.off += n
+	return in
+}
+
+// Pos returns the position of the start of the input in the string being parsed.
+func (in Input) Pos() Pos {
+	before := in.src[:in.off]
+	line := before[strings.LastIndexByte(before, '\n')+1:]
+	return Pos{
+		Offset: in.off,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: utf8.RuneCountInString(line) + 1,
+	}
+}
+
+// Expected returns an error reporting that the input doesn't start with what was expected, which
+// is described as, say, "a number".
+func (in Input) Expected(what string) *Error {
+	err := &Error{Pos: in.Pos(), Expected: []string{what}, Found: in.next()}
+	if in.state != nil {
+		in.state.record(err)
+	}
+	return err
+}
+
+// next describes the next rune of the input, for errors.
+func (in Input) next() string {
+	if r, size := utf8.DecodeRuneInString(in.Rest()); size > 0 {
+		return strconv.QuoteRune(r)
+	}
+	return "end of input"
+}
+
+// record keeps the furthest failure, merging what was expected by failures at the same position.
+func (s *state) record(err *Error) {
+	switch {
+	case s.furthest == nil || err.Pos.Offset > s.furthest.Pos.Offset:
+		s.furthest = &Error{
+			Pos:      err.Pos,
+			Expected: append([]string(nil), err.Expected...),
+			Found:    err.Found,
+		}
+	case err.Pos.Offset == s.furthest.Pos.Offset:
+	expected:
+		for _, e := range err.Expected {
+			for _, seen := range s.furthest.Expected {
+				if e == seen {
+					continue expected
+				}
+			}
+			s.furthest.Expected = append(s.furthest.Expected, e)
+		}
+	}
+}
+
+// Pos is a position in the string being parsed. Lines and columns count from 1, and columns
+// count runes.
+type Pos struct {
+	Offset, Line, Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error is a parse error, such as `2:8: expected ": ", found ' '`.
+type Error struct {
+	Pos Pos
+	// descriptions of everything which would have been accepted at Pos
+	Expected []string
+	// a description of what was there instead
+	Found string
+}
+
+func (e *Error) Error() string {
+	if len(e.Expected) == 0 {
+		return fmt.Sprintf("%s: unexpected %s", e.Pos, e.Found)
+	}
+	return fmt.Sprintf("%s: expected %s, found %s", e.Pos, strings.Join(e.Expected, " or "), e.Found)
+}
+
+// Parse parses all of s with p. If p fails, or doesn't parse all of s, Parse returns the failure
+// which got furthest into s, since that's usually where s went wrong, even if p recovered from it.
+func Parse[T any](p Parser[T], s string) (T, error) {
+	in := NewInput(s)
+	v, rest, err := p(in)
+	if err == nil && rest.off < len(s) {
+		err = rest.Expected("end of input")
+	}
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && in.state.furthest != nil {
+			err = in.state.furthest
+		}
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// String returns a parser of s itself.
+func String(s string) Parser[string] {
+	what := strconv.Quote(s)
+	return func(in Input) (string, Input, error) {
+		if !strings.HasPrefix(in.Rest(), s) {
+			return "", in, in.Expected(what)
+		}
+		return s, in.Advance(len(s)), nil
+	}
+}
+
+// Regexp returns a parser of text matching the regexp, which panics if the regexp can't be
+// compiled.
+func Regexp(pattern string) Parser[string] {
+	re := regexp.MustCompile(`\A(?:` + pattern + `)`)
+	what := "text matching " + pattern
+	return func(in Input) (string, Input, error) {
+		loc := re.FindStringIndex(in.Rest())
+		if loc == nil {
+			return "", in, in.Expected(what)
+		}
+		return in.Rest()[:loc[1]], in.Advance(loc[1]), nil
+	}
+}
+
+// Map returns a parser which parses a T with p, and converts it to a U with f.
+func Map[T, U any](p Parser[T], f func(T) U) Parser[U] {
+	return func(in Input) (U, Input, error) {
+		v, rest, err := p(in)
+		if err != nil {
+			var zero U
+			return zero, in, err
+		}
+		return f(v), rest, nil
+	}
+}
+
+// Or returns a parser which tries each of the given parsers in turn, and returns the result of the
+// first to succeed. If they all fail, it fails with the error which got furthest into the input.
+// Errors which aren't *Errors stop it from trying any more parsers.
+func Or[T any](ps ...Parser[T]) Parser[T] {
+	return func(in Input) (T, Input, error) {
+		var zero T
+		var furthest *Error
+		for _, p := range ps {
+			v, rest, err := p(in)
+			if err == nil {
+				return v, rest, nil
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				return zero, in, err
+			}
+			if furthest == nil || e.Pos.Offset > furthest.Pos.Offset {
+				furthest = e
+			}
+		}
+		if furthest == nil {
+			return zero, in, &Error{Pos: in.Pos(), Found: in.next()}
+		}
+		return zero, in, furthest
+	}
+}
+
+// Many returns a parser which parses as many Ts with p as it can, one after another, which may be
+// none at all. It stops at the first failure, or when p stops consuming input. Errors which
+// aren't *Errors make it fail.
+func Many[T any](p Parser[T]) Parser[[]T] {
+	return func(start Input) ([]T, Input, error) {
+		var vs []T
+		in := start
+		for {
+			v, rest, err := p(in)
+			var e *Error
+			if err != nil && !errors.As(err, &e) {
+				return nil, start, err
+			}
+			if err != nil || rest.off == in.off {
+				return vs, in, nil
+			}
+			vs = append(vs, v)
+			in = rest
+		}
+	}
+}
+"#;
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	// Seq for 4 parsers, and the parsers themselves
+	parse4 "pkg.golang.fail/parse/4/parse"
+	"pkg.golang.fail/parse/parser"
+)
+
+var (
+	word   = parser.Regexp(`[a-z]+`)
+	number = parser.Map(parser.Regexp(`[0-9]+`), func(s string) any {
+		n, _ := strconv.Atoi(s)
+		return n
+	})
+	value = parser.Or(number, parser.Map(word, func(s string) any { return s }))
+
+	// a header such as "retries: 3\n", parsed into a tuple.Tuple[string, string, any, string]
+	header  = parse4.Seq(word, parser.String(": "), value, parser.String("\n"))
+	headers = parser.Many(header)
+)
+
+func main() {
+	hs, err := parser.Parse(headers, "host: example\nretries: 3\n")
+	fmt.Println(err) // <nil>
+	for _, h := range hs {
+		name, _, value, _ := h.Unpack()
+		fmt.Printf("%s = %#v\n", name, value) // host = "example", then retries = 3
+	}
+
+	// errors report the furthest point any parser got to, and what it expected there
+	_, err = parser.Parse(headers, "host: example\nretries 3\n")
+	fmt.Println(err) // 2:8: expected ": ", found ' '
+	_, err = parser.Parse(headers, "host: example\nretries: ?\n")
+	fmt.Println(err) // 2:10: expected text matching [0-9]+ or text matching [a-z]+, found '?'
+}
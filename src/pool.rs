@@ -0,0 +1,142 @@
+// The generation pool, which runs repo generation (and mirroring), and other slow work such as
+// rendering modules' files for the API and badges, on tokio's blocking threads, a bounded number
+// at a time, so that a stampede of new repos neither stalls the async runtime's workers, which
+// serve every other request, nor spawns a thread per repo.
+//
+// The limit defaults to 4, and may be set with the GENERATION_LIMIT environment variable. The
+// number of repos waiting for a turn, and being generated, are exported at '/metrics'.
+//
+// Requests for a repo that's already being generated wait for that generation (see flight), rather
+// than taking turns of their own.
+
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex, OnceLock};
+
+use tokio::sync::{OwnedMutexGuard, Semaphore};
+
+const DEFAULT_LIMIT: usize = 4;
+
+pub struct Pool {
+    permits: Semaphore,
+    limit: usize,
+    queued: AtomicUsize,
+    running: AtomicUsize,
+}
+
+// pool returns the process's generation pool.
+pub fn pool() -> &'static Pool {
+    static POOL: OnceLock<Pool> = OnceLock::new();
+    POOL.get_or_init(|| {
+        let limit = match std::env::var("GENERATION_LIMIT") {
+            Ok(limit) => limit
+                .parse()
+                .ok()
+                .filter(|&l| l > 0)
+                .expect("GENERATION_LIMIT must be a positive number"),
+            Err(_) => DEFAULT_LIMIT,
+        };
+        Pool {
+            permits: Semaphore::new(limit),
+            limit,
+            queued: AtomicUsize::new(0),
+            running: AtomicUsize::new(0),
+        }
+    })
+}
+
+impl Pool {
+    // run waits for a turn, and then runs f on a blocking thread, returning its result.
+    // If the caller stops waiting (say, because its client went away) after f has started, f still
+    // runs to completion, and keeps its turn until then.
+    pub async fn run<T: Send + 'static>(
+        &'static self,
+        f: impl FnOnce() -> T + Send + 'static,
+    ) -> Result<T, anyhow::Error> {
+        let queued = Gauge::inc(&self.queued);
+        let permit = self.permits.acquire().await?;
+        drop(queued);
+
+        let running = Gauge::inc(&self.running);
+        Ok(tokio::task::spawn_blocking(move || {
+            let _permit = permit;
+            let _running = running;
+            f()
+        })
+        .await?)
+    }
+
+    pub fn limit(&self) -> usize {
+        self.limit
+    }
+
+    // queued returns the number of callers waiting for a turn.
+    pub fn queued(&self) -> usize {
+        self.queued.load(Ordering::Relaxed)
+    }
+
+    pub fn running(&self) -> usize {
+        self.running.load(Ordering::Relaxed)
+    }
+}
+
+// Gauge increments a counter for as long as it's alive.
+struct Gauge(&'static AtomicUsize);
+
+impl Gauge {
+    fn inc(counter: &'static AtomicUsize) -> Gauge {
+        counter.fetch_add(1, Ordering::Relaxed);
+        Gauge(counter)
+    }
+}
+
+impl Drop for Gauge {
+    fn drop(&mut self) {
+        self.0.fetch_sub(1, Ordering::Relaxed);
+    }
+}
+
+// A Flight is the turn to create a repo. Only one caller in the process has the turn for a given
+// repo at a time, so callers which find the repo missing should take it, and then check again
+// before creating it: if it's there now, the caller before them created it.
+pub struct Flight {
+    path: PathBuf,
+    _turn: OwnedMutexGuard<()>,
+}
+
+fn flights() -> &'static Mutex<HashMap<PathBuf, Arc<tokio::sync::Mutex<()>>>> {
+    static FLIGHTS: OnceLock<Mutex<HashMap<PathBuf, Arc<tokio::sync::Mutex<()>>>>> =
+        OnceLock::new();
+    FLIGHTS.get_or_init(Default::default)
+}
+
+// flight waits for the turn to create the repo at path. If the caller with the turn stops waiting
+// for its repo, the next caller gets the turn, even though the repo may still be in progress on
+// the pool; init_repo's lock covers that.
+pub async fn flight(path: &Path) -> Flight {
+    let turn = flights()
+        .lock()
+        .unwrap()
+        .entry(path.to_path_buf())
+        .or_default()
+        .clone();
+    Flight {
+        path: path.to_path_buf(),
+        _turn: turn.lock_owned().await,
+    }
+}
+
+impl Drop for Flight {
+    fn drop(&mut self) {
+        let mut flights = flights().lock().unwrap();
+        // forget the repo's turn once nobody else is waiting for it: the map and this flight's
+        // guard are the only references left
+        if flights
+            .get(&self.path)
+            .map_or(false, |turn| Arc::strong_count(turn) == 2)
+        {
+            flights.remove(&self.path);
+        }
+    }
+}
@@ -0,0 +1,143 @@
+// The fixed-capacity ring buffer module template.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791964800;
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/ring/{}", n)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.23\n", module_path(n)).into_bytes(),
+        ),
+        ("ring.go", ring_go(n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn ring_go(n: u64) -> String {
+    format!(
+        r#"// Package ring provides a ring buffer with a fixed capacity of {n}.
+//
+// Rings store their values inline, in a [{n}]T, so they never allocate, and may be copied like any
+// other value. Since the capacity is a constant, indexing into the ring needs no bounds checks.
+package ring
+
+import "iter"
+
+// Cap is the capacity of every Ring in this package.
+const Cap = {n}
+
+// Mode is what a Ring does when a value is pushed while it's full.
+type Mode uint8
+
+const (
+	// Overwrite makes Push replace the oldest value in a full ring. It's the zero value.
+	Overwrite Mode = iota
+	// Reject makes Push leave a full ring unchanged, and report that it did so.
+	Reject
+)
+
+// Ring is a first-in, first-out queue of up to {n} values.
+// The zero value is an empty ring in Overwrite mode.
+type Ring[T any] struct {{
+	buf  [{n}]T
+	head uint // index of the oldest value
+	len  uint
+	mode Mode
+}}
+
+// New returns an empty ring in the given mode.
+func New[T any](mode Mode) Ring[T] {{
+	return Ring[T]{{mode: mode}}
+}}
+
+// Len returns the number of values in the ring.
+func (r *Ring[T]) Len() int {{
+	return int(r.len)
+}}
+
+// Full reports whether the ring holds {n} values.
+func (r *Ring[T]) Full() bool {{
+	return r.len == Cap
+}}
+
+// Push adds v as the newest value in the ring. If the ring is full, it either overwrites the
+// oldest value or, in Reject mode, returns false without adding v.
+func (r *Ring[T]) Push(v T) bool {{
+	if r.len == Cap {{
+		if r.mode == Reject {{
+			return false
+		}}
+		r.buf[r.head%Cap] = v
+		r.head = (r.head + 1) % Cap
+		return true
+	}}
+	r.buf[(r.head+r.len)%Cap] = v
+	r.len++
+	return true
+}}
+
+// Pop removes and returns the oldest value in the ring, or returns false if it's empty.
+func (r *Ring[T]) Pop() (T, bool) {{
+	var zero T
+	if r.len == 0 {{
+		return zero, false
+	}}
+	v := r.buf[r.head%Cap]
+	r.buf[r.head%Cap] = zero
+	r.head = (r.head + 1) % Cap
+	r.len--
+	return v, true
+}}
+
+// Peek returns the oldest value in the ring without removing it, or returns false if it's empty.
+func (r *Ring[T]) Peek() (T, bool) {{
+	if r.len == 0 {{
+		var zero T
+		return zero, false
+	}}
+	return r.buf[r.head%Cap], true
+}}
+
+// At returns the i'th oldest value in the ring, where 0 is the oldest. It panics if i is out of
+// range.
+func (r *Ring[T]) At(i int) T {{
+	if i < 0 || uint(i) >= r.len {{
+		panic("ring: index out of range")
+	}}
+	return r.buf[(r.head+uint(i))%Cap]
+}}
+
+// All returns an iterator over the indexes and values in the ring, oldest first.
+func (r *Ring[T]) All() iter.Seq2[int, T] {{
+	return func(yield func(int, T) bool) {{
+		for i := uint(0); i < r.len; i++ {{
+			if !yield(int(i), r.buf[(r.head+i)%Cap]) {{
+				return
+			}}
+		}}
+	}}
+}}
+
+// AppendTo appends the values in the ring to dst, oldest first, and returns the extended slice.
+func (r *Ring[T]) AppendTo(dst []T) []T {{
+	for i := uint(0); i < r.len; i++ {{
+		dst = append(dst, r.buf[(r.head+i)%Cap])
+	}}
+	return dst
+}}
+
+// Clear removes every value from the ring.
+func (r *Ring[T]) Clear() {{
+	*r = Ring[T]{{mode: r.mode}}
+}}
+"#
+    )
+}
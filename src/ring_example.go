@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	// import a ring buffer with space for 60 values
+	ring60 "pkg.golang.fail/ring/60"
+)
+
+// a minute's worth of per-second samples
+type window struct {
+	samples ring60.Ring[float64]
+}
+
+func (w *window) record(v float64) {
+	// once full, each new sample replaces the oldest one
+	w.samples.Push(v)
+}
+
+func (w *window) mean() float64 {
+	sum := 0.0
+	for _, v := range w.samples.All() {
+		sum += v
+	}
+	return sum / float64(w.samples.Len())
+}
+
+func main() {
+	var w window
+	for i := 0; i < 120; i++ {
+		w.record(float64(i))
+	}
+	fmt.Println(w.mean()) // 89.5, the mean of the last 60 samples
+
+	// rings are values, so snapshotting one is just a copy
+	snapshot := w.samples
+	w.samples.Clear()
+	fmt.Println(snapshot.Len(), w.samples.Len()) // 60 0
+
+	// in Reject mode, pushing to a full ring fails instead
+	queue := ring60.New[time.Time](ring60.Reject)
+	for queue.Push(time.Now()) {
+	}
+	fmt.Println(queue.Full()) // true
+}
@@ -0,0 +1,146 @@
+// The n-argument signal module template: a list of typed callbacks, called on every emission.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791979200;
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/signal/{}/signal", n)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.19\n", module_path(n)).into_bytes(),
+        ),
+        ("signal.go", signal_go(n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn signal_go(n: u64) -> String {
+    let types = (0..n)
+        .map(|i| format!("T{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    // a 0-argument signal isn't generic at all
+    let (decl, inst) = if n == 0 {
+        ("".to_string(), "".to_string())
+    } else {
+        (format!("[{} any]", types), format!("[{}]", types))
+    };
+    let params = (0..n)
+        .map(|i| format!("v{} T{}", i, i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let args = (0..n)
+        .map(|i| format!("v{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let arguments = if n == 1 { "argument" } else { "arguments" };
+
+    format!(
+        r#"// Package signal provides signals: lists of callbacks taking {n} {arguments}, which are all called
+// whenever the signal is emitted.
+package signal
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Signal is a list of callbacks which are called, in the order they were connected, whenever the
+// signal is emitted. The zero value is a signal with no callbacks.
+//
+// Signals are safe for concurrent use. Callbacks may be connected and disconnected while the
+// signal is being emitted, including by the callbacks themselves: each emission calls the
+// callbacks which were connected when it began, other than those disconnected before it reaches
+// them. A Signal must not be copied after first use.
+type Signal{decl} struct {{
+	mu sync.Mutex
+	// never modified in place, so emissions can iterate over it without holding mu
+	slots []*slot{inst}
+}}
+
+type slot{decl} struct {{
+	f            func({types})
+	disconnected atomic.Bool
+}}
+
+// Connect adds f to the signal's callbacks, and returns a function which removes it again.
+// Calling disconnect more than once has no further effect.
+func (s *Signal{inst}) Connect(f func({types})) (disconnect func()) {{
+	sl := &slot{inst}{{f: f}}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slots = append(s.slots[:len(s.slots):len(s.slots)], sl)
+
+	return func() {{
+		if sl.disconnected.Swap(true) {{
+			return
+		}}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		slots := make([]*slot{inst}, 0, len(s.slots)-1)
+		for _, o := range s.slots {{
+			if o != sl {{
+				slots = append(slots, o)
+			}}
+		}}
+		s.slots = slots
+	}}
+}}
+
+// Len returns the number of callbacks connected to the signal.
+func (s *Signal{inst}) Len() int {{
+	return len(s.snapshot())
+}}
+
+// Emit calls each of the signal's callbacks, in the order they were connected, and returns once
+// they all have.
+func (s *Signal{inst}) Emit({params}) {{
+	emit(s.snapshot(){comma_args})
+}}
+
+// EmitAsync is like Emit, but calls the callbacks on a new goroutine, and returns without waiting
+// for them. Which callbacks are called is decided before EmitAsync returns. The returned channel
+// is closed once every callback has returned.
+func (s *Signal{inst}) EmitAsync({params}) <-chan struct{{}} {{
+	slots := s.snapshot()
+	done := make(chan struct{{}})
+	go func() {{
+		defer close(done)
+		emit(slots{comma_args})
+	}}()
+	return done
+}}
+
+func (s *Signal{inst}) snapshot() []*slot{inst} {{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slots
+}}
+
+func emit{decl}(slots []*slot{inst}{comma_params}) {{
+	for _, sl := range slots {{
+		if !sl.disconnected.Load() {{
+			sl.f({args})
+		}}
+	}}
+}}
+"#,
+        comma_args = if n == 0 {
+            "".to_string()
+        } else {
+            format!(", {}", args)
+        },
+        comma_params = if n == 0 {
+            "".to_string()
+        } else {
+            format!(", {}", params)
+        },
+    )
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	// import a signal whose callbacks take 2 arguments
+	signal2 "pkg.golang.fail/signal/2/signal"
+)
+
+type thermostat struct {
+	// called with the old and new temperature whenever it changes
+	Changed signal2.Signal[float64, float64]
+	temp    float64
+}
+
+func (t *thermostat) set(temp float64) {
+	old := t.temp
+	t.temp = temp
+	t.Changed.Emit(old, temp)
+}
+
+func main() {
+	var t thermostat
+	disconnect := t.Changed.Connect(func(old, new float64) {
+		fmt.Printf("%.1f -> %.1f\n", old, new)
+	})
+	t.set(20.5) // 0.0 -> 20.5
+
+	// callbacks may disconnect themselves, or anything else, mid-emission
+	var once func()
+	once = t.Changed.Connect(func(_, new float64) {
+		fmt.Println("first change after connecting:", new)
+		once()
+	})
+	t.set(21) // 20.5 -> 21.0, and then first change after connecting: 21
+	t.set(22) // 21.0 -> 22.0
+
+	disconnect()
+	t.set(23) // nothing
+
+	// EmitAsync doesn't wait for the callbacks, but tells you when they're done
+	t.Changed.Connect(func(_, new float64) { fmt.Println("async:", new) })
+	<-t.Changed.EmitAsync(23, 24) // async: 24
+}
@@ -0,0 +1,212 @@
+// The small-vector module template: a slice-like vector which stores up to n values inline.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791972000;
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/smallvec/{}", n)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.23\n", module_path(n)).into_bytes(),
+        ),
+        ("smallvec.go", smallvec_go(n).into_bytes()),
+        ("smallvec_test.go", smallvec_test_go().into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn smallvec_go(n: u64) -> String {
+    format!(
+        r#"// Package smallvec provides a vector which stores up to {n} values inline, only allocating
+// once it grows past that.
+package smallvec
+
+import "iter"
+
+// InlineCap is the number of values a SmallVec stores before it allocates.
+const InlineCap = {n}
+
+// SmallVec is a growable list of values. The zero value is an empty vector.
+//
+// Its first {n} values are stored inline, in the SmallVec itself. Past that, it spills every value
+// to a slice on the heap, which it grows like append does. Copying a SmallVec which hasn't spilled
+// copies its values; copying one which has shares the values, like copying a slice does.
+type SmallVec[T any] struct {{
+	inline [{n}]T
+	len    int // number of values in inline, until spilled
+	heap   []T // every value, once spilled
+}}
+
+// Append adds values to the end of the vector.
+func (v *SmallVec[T]) Append(values ...T) {{
+	if v.heap == nil {{
+		if len(values) <= InlineCap-v.len {{
+			v.len += copy(v.inline[v.len:], values)
+			return
+		}}
+		v.heap = make([]T, v.len, 2*(v.len+len(values)))
+		copy(v.heap, v.inline[:v.len])
+		v.inline = [{n}]T{{}}
+		v.len = 0
+	}}
+	v.heap = append(v.heap, values...)
+}}
+
+// Len returns the number of values in the vector.
+func (v *SmallVec[T]) Len() int {{
+	if v.heap != nil {{
+		return len(v.heap)
+	}}
+	return v.len
+}}
+
+// Spilled reports whether the vector has grown past InlineCap, and so stores its values on the
+// heap.
+func (v *SmallVec[T]) Spilled() bool {{
+	return v.heap != nil
+}}
+
+// At returns the i'th value in the vector. It panics if i is out of range.
+func (v *SmallVec[T]) At(i int) T {{
+	return v.Slice()[i]
+}}
+
+// Set replaces the i'th value in the vector. It panics if i is out of range.
+func (v *SmallVec[T]) Set(i int, value T) {{
+	v.Slice()[i] = value
+}}
+
+// All returns an iterator over the indexes and values in the vector, in order.
+func (v *SmallVec[T]) All() iter.Seq2[int, T] {{
+	return func(yield func(int, T) bool) {{
+		for i, value := range v.Slice() {{
+			if !yield(i, value) {{
+				return
+			}}
+		}}
+	}}
+}}
+
+// Slice returns the values in the vector as a slice, without copying them. The slice refers to
+// the vector's storage, so it's only valid until the vector is next appended to, and, if the
+// vector hasn't spilled, as long as the vector isn't moved or copied.
+func (v *SmallVec[T]) Slice() []T {{
+	if v.heap != nil {{
+		return v.heap
+	}}
+	return v.inline[:v.len:v.len]
+}}
+"#
+    )
+}
+
+fn smallvec_test_go() -> String {
+    r#"package smallvec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	var v SmallVec[int]
+	var want []int
+	for i := 0; i < 3*InlineCap+1; i++ {
+		if got := v.Slice(); !slices.Equal(got, want) {
+			t.Fatalf("after %d appends, got %v, want %v", i, got, want)
+		}
+		if spilled := v.Spilled(); spilled != (i > InlineCap) {
+			t.Fatalf("after %d appends, Spilled() = %v", i, spilled)
+		}
+		v.Append(i)
+		want = append(want, i)
+	}
+	for i, value := range v.All() {
+		if value != want[i] || v.At(i) != want[i] {
+			t.Fatalf("value %d is %d, want %d", i, value, want[i])
+		}
+	}
+}
+
+func TestInlineCopy(t *testing.T) {
+	var v SmallVec[int]
+	v.Append(1)
+	c := v
+	c.Set(0, 2)
+	if v.At(0) != 1 {
+		t.Fatalf("copy of an inline vector shares its values")
+	}
+}
+
+func TestInlineAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		var v SmallVec[int]
+		for i := 0; i < InlineCap; i++ {
+			v.Append(i)
+		}
+		sink = v.Len()
+	})
+	if allocs != 0 {
+		t.Fatalf("filling a vector to InlineCap allocated %v times", allocs)
+	}
+}
+
+var sink int
+
+func BenchmarkSmallVecAppend(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var v SmallVec[int]
+		for j := 0; j < InlineCap; j++ {
+			v.Append(j)
+		}
+		sink = v.Len()
+	}
+}
+
+func BenchmarkSliceAppend(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var s []int
+		for j := 0; j < InlineCap; j++ {
+			s = append(s, j)
+		}
+		sinkSlice = s
+	}
+}
+
+var sinkSlice []int
+
+func BenchmarkSmallVecIterate(b *testing.B) {
+	var v SmallVec[int]
+	for j := 0; j < InlineCap; j++ {
+		v.Append(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, value := range v.All() {
+			sink += value
+		}
+	}
+}
+
+func BenchmarkSliceIterate(b *testing.B) {
+	var s []int
+	for j := 0; j < InlineCap; j++ {
+		s = append(s, j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, value := range s {
+			sink += value
+		}
+	}
+}
+"#
+    .into()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	// import a small vector with space for 4 values inline
+	smallvec4 "pkg.golang.fail/smallvec/4"
+)
+
+// most words have only a few vowels, so collecting their positions rarely allocates
+func vowels(word string) smallvec4.SmallVec[int] {
+	var positions smallvec4.SmallVec[int]
+	for i, r := range word {
+		if strings.ContainsRune("aeiou", r) {
+			positions.Append(i)
+		}
+	}
+	return positions
+}
+
+func main() {
+	v := vowels("gopher")
+	fmt.Println(v.Len(), v.Spilled()) // 2 false
+
+	v = vowels("onomatopoeia")
+	fmt.Println(v.Len(), v.Spilled()) // 8 true
+
+	for i, pos := range v.All() {
+		fmt.Println(i, pos)
+	}
+
+	// Slice gives you the values as a plain slice, without copying them
+	fmt.Println(v.Slice()) // [0 2 4 6 8 9 10 11]
+}
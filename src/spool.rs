@@ -0,0 +1,258 @@
+// Capture and replay of failing git requests, for reproducing intermittent clone failures.
+//
+// When the GIT_SPOOL environment variable names a directory, each git request for a generated repo
+// that fails with an unhandled error is saved there, as '<id>.json' (the repo, method, uri,
+// headers and error) and '<id>.body'. Credentials, such as Authorization and Cookie headers, are
+// redacted. Only the newest GIT_SPOOL_LIMIT (default 100) requests are kept, though the request
+// just captured always is. The 'replay' subcommand sends captured requests to a local instance
+// again, without their redacted headers:
+//
+//   pkg-golang-fail replay [--addr 127.0.0.1:8080] spool/<id>.json...
+
+use std::io::{Read, Write};
+use std::net::TcpStream;
+use std::path::{Path, PathBuf};
+
+use serde::{Deserialize, Serialize};
+
+const DEFAULT_LIMIT: usize = 100;
+
+// headers whose values are credentials, which are never written to the spool
+const REDACTED_HEADERS: &[&str] = &["authorization", "proxy-authorization", "cookie"];
+const REDACTED: &str = "<redacted>";
+
+#[derive(Serialize, Deserialize)]
+pub struct Capture {
+    // the repo's name, such as 'tuple/3/tuple' (see init_repo)
+    pub repo: String,
+    pub method: String,
+    pub uri: String,
+    pub headers: Vec<(String, String)>,
+    pub error: String,
+    // seconds since the unix epoch
+    pub time: u64,
+}
+
+impl Capture {
+    pub fn new(repo: &str, req: &axum::http::request::Parts) -> Capture {
+        Capture {
+            repo: repo.to_string(),
+            method: req.method.to_string(),
+            uri: req.uri.to_string(),
+            headers: req
+                .headers
+                .iter()
+                .map(|(k, v)| {
+                    // header names are lowercase
+                    let v = if REDACTED_HEADERS.contains(&k.as_str()) {
+                        REDACTED.to_string()
+                    } else {
+                        String::from_utf8_lossy(v.as_bytes()).into_owned()
+                    };
+                    (k.to_string(), v)
+                })
+                .collect(),
+            error: String::new(),
+            time: std::time::SystemTime::now()
+                .duration_since(std::time::UNIX_EPOCH)
+                .unwrap_or_default()
+                .as_secs(),
+        }
+    }
+}
+
+// dir returns the spool directory, if capturing is enabled.
+pub fn dir() -> Option<PathBuf> {
+    std::env::var_os("GIT_SPOOL")
+        .filter(|d| !d.is_empty())
+        .map(PathBuf::from)
+}
+
+fn limit() -> usize {
+    std::env::var("GIT_SPOOL_LIMIT")
+        .ok()
+        .and_then(|l| l.parse().ok())
+        .unwrap_or(DEFAULT_LIMIT)
+}
+
+// save adds a request to the spool, dropping the oldest requests beyond the limit, and returns
+// the path of its '.json' file.
+pub fn save(dir: &Path, capture: &Capture, body: &[u8]) -> Result<PathBuf, anyhow::Error> {
+    save_with_limit(dir, capture, body, limit())
+}
+
+fn save_with_limit(
+    dir: &Path,
+    capture: &Capture,
+    body: &[u8],
+    limit: usize,
+) -> Result<PathBuf, anyhow::Error> {
+    std::fs::create_dir_all(dir)?;
+    let nanos = std::time::SystemTime::now()
+        .duration_since(std::time::UNIX_EPOCH)
+        .unwrap_or_default()
+        .as_nanos();
+    // ids sort oldest first
+    let id = format!("{:020}-{}", nanos, capture.repo.replace('/', "-"));
+    std::fs::write(dir.join(format!("{}.body", id)), body)?;
+    let path = dir.join(format!("{}.json", id));
+    std::fs::write(&path, serde_json::to_vec_pretty(capture)?)?;
+
+    let mut ids = std::fs::read_dir(dir)?
+        .filter_map(|e| e.ok())
+        .filter_map(|e| {
+            e.file_name()
+                .to_str()
+                .and_then(|name| name.strip_suffix(".json"))
+                .map(String::from)
+        })
+        .collect::<Vec<_>>();
+    ids.sort();
+    // never this request, which may not be the newest if another was captured meanwhile
+    ids.retain(|i| *i != id);
+    let excess = ids.len().saturating_sub(limit.saturating_sub(1));
+    for id in &ids[..excess] {
+        // another request may be pruning the same files
+        let _ = std::fs::remove_file(dir.join(format!("{}.json", id)));
+        let _ = std::fs::remove_file(dir.join(format!("{}.body", id)));
+    }
+    Ok(path)
+}
+
+pub fn main(args: &[String]) -> i32 {
+    let mut addr = "127.0.0.1:8080".to_string();
+    let mut captures = Vec::new();
+
+    let mut args = args.iter();
+    while let Some(arg) = args.next() {
+        if arg == "--addr" {
+            match args.next() {
+                Some(a) => addr = a.clone(),
+                None => {
+                    eprintln!("--addr must be a host and port, such as '127.0.0.1:8080'");
+                    return 2;
+                }
+            }
+        } else {
+            captures.push(PathBuf::from(arg));
+        }
+    }
+    if captures.is_empty() {
+        eprintln!("usage: pkg-golang-fail replay [--addr 127.0.0.1:8080] <capture.json>...");
+        return 2;
+    }
+
+    let mut failed = 0;
+    for path in &captures {
+        match replay(&addr, path) {
+            Ok((capture, status)) => {
+                println!(
+                    "{}: {} {} {}",
+                    path.display(),
+                    capture.repo,
+                    capture.method,
+                    capture.uri
+                );
+                println!("  captured: {}", capture.error);
+                println!("  replayed: {}", status);
+                if !status.starts_with("HTTP/1.1 200 ") {
+                    failed += 1;
+                }
+            }
+            Err(e) => {
+                println!("{}: could not replay: {}", path.display(), e);
+                failed += 1;
+            }
+        }
+    }
+    if failed > 0 {
+        1
+    } else {
+        0
+    }
+}
+
+// replay sends a captured request to addr, returning the capture and the response's status line.
+fn replay(addr: &str, path: &Path) -> Result<(Capture, String), anyhow::Error> {
+    let capture: Capture = serde_json::from_slice(&std::fs::read(path)?)?;
+    let body = std::fs::read(path.with_extension("body"))?;
+
+    let mut req = format!(
+        "{} {} HTTP/1.1\r\nHost: {}\r\n",
+        capture.method, capture.uri, addr
+    );
+    for (k, v) in &capture.headers {
+        // the body was captured whole, and each replay gets its own connection
+        if ["host", "connection", "content-length", "transfer-encoding"].contains(&k.as_str())
+            || v == REDACTED
+        {
+            continue;
+        }
+        req.push_str(&format!("{}: {}\r\n", k, v));
+    }
+    req.push_str(&format!(
+        "Connection: close\r\nContent-Length: {}\r\n\r\n",
+        body.len()
+    ));
+
+    let mut stream = TcpStream::connect(addr)?;
+    stream.write_all(req.as_bytes())?;
+    stream.write_all(&body)?;
+    let mut resp = Vec::new();
+    stream.read_to_end(&mut resp)?;
+    let status = String::from_utf8_lossy(&resp)
+        .lines()
+        .next()
+        .unwrap_or_default()
+        .to_string();
+    if status.is_empty() {
+        anyhow::bail!("empty response");
+    }
+    Ok((capture, status))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn redacts_credentials() {
+        let (parts, _) = axum::http::Request::builder()
+            .uri("/vector/3.git/git-upload-pack")
+            .header("Authorization", "Basic dXNlcjpwYXNz")
+            .header("Cookie", "session=secret")
+            .header("User-Agent", "git/2.39.2")
+            .body(())
+            .unwrap()
+            .into_parts();
+        let capture = Capture::new("vector/3", &parts);
+        assert_eq!(
+            capture.headers,
+            [
+                ("authorization".to_string(), REDACTED.to_string()),
+                ("cookie".to_string(), REDACTED.to_string()),
+                ("user-agent".to_string(), "git/2.39.2".to_string()),
+            ]
+        );
+    }
+
+    #[test]
+    fn keeps_the_new_capture() {
+        let dir = tempfile::TempDir::new().unwrap();
+        let (parts, _) = axum::http::Request::builder()
+            .body(())
+            .unwrap()
+            .into_parts();
+        let capture = Capture::new("vector/3", &parts);
+        let mut kept = Vec::new();
+        for _ in 0..3 {
+            let path = save_with_limit(dir.path(), &capture, b"body", 0).unwrap();
+            assert!(path.exists());
+            assert!(path.with_extension("body").exists());
+            kept.push(path);
+        }
+        // only the newest one is left
+        assert_eq!(std::fs::read_dir(dir.path()).unwrap().count(), 2);
+        assert!(!kept[0].exists());
+    }
+}
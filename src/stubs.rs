@@ -0,0 +1,47 @@
+// The 'stubs' subcommand, which prints the type of a tuple as encoding/json encodes it in another
+// language, or the schema JSONSchema returns for it encoded as an array, given the go types of its
+// elements:
+//
+//   pkg-golang-fail stubs [--lang ts|py|schema] string int '[]byte'
+//
+// The output is the same as that of '/tuple/:n/types.ts', '/tuple/:n/types.py' and
+// '/tuple/:n/schema.json'.
+
+use crate::gotype;
+
+pub fn main(args: &[String]) -> i32 {
+    let mut lang = "ts";
+    let mut types = Vec::new();
+
+    let mut args = args.iter();
+    while let Some(arg) = args.next() {
+        if arg == "--lang" {
+            match args.next().map(String::as_str) {
+                Some(l @ ("ts" | "py" | "schema")) => lang = l,
+                _ => {
+                    eprintln!("--lang must be one of 'ts', 'py' or 'schema'");
+                    return 2;
+                }
+            }
+        } else {
+            match gotype::parse(arg) {
+                Ok(t) => types.push(t),
+                Err(e) => {
+                    eprintln!("{}", e);
+                    return 2;
+                }
+            }
+        }
+    }
+
+    match lang {
+        "ts" => print!("{}", gotype::tuple_typescript(&types)),
+        "py" => print!("{}", gotype::tuple_python(&types)),
+        _ => {
+            let elems = types.iter().map(|t| t.json_schema()).collect();
+            let schema = gotype::tuple_json_schema(elems);
+            println!("{}", serde_json::to_string_pretty(&schema).unwrap());
+        }
+    }
+    0
+}
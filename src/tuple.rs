@@ -0,0 +1,879 @@
+// The n-ary tuple module template, along with every release of it that we've published.
+//
+// Generated repos contain one commit per release, each tagged with its version, so that once a
+// version has been published its commit (and therefore its go.sum hash) never changes. New
+// template changes must be appended as a new release rather than editing an existing one.
+
+pub struct Release {
+    pub version: &'static str,
+    pub message: &'static str,
+    // commit time, fixed so generated repos are reproducible
+    pub time: i64,
+    pub go: &'static str,
+    // JSONSchema describes a tuple encoded as a JSON array
+    pub json_schema: bool,
+    // Intern interns comparable tuples with the unique package, which needs go 1.23
+    pub intern: bool,
+    // README.md and CHANGELOG.md describe the module and its releases
+    pub docs: bool,
+    // JSONSchema's doc says that encoding/json encodes a Tuple as an object, not as the array it
+    // describes
+    pub schema_caveat: bool,
+}
+
+pub const RELEASES: &[Release] = &[
+    Release {
+        version: "v1.0.0",
+        message: "Initial commit",
+        time: 420,
+        go: "1.18",
+        json_schema: false,
+        intern: false,
+        docs: false,
+        schema_caveat: false,
+    },
+    Release {
+        version: "v1.1.0",
+        message: "Add JSONSchema, describing a tuple encoded as a JSON array",
+        time: 1791936000,
+        go: "1.18",
+        json_schema: true,
+        intern: false,
+        docs: false,
+        schema_caveat: false,
+    },
+    Release {
+        version: "v1.2.0",
+        message: "Add Intern, interning comparable tuples with the unique package",
+        time: 1792029600,
+        go: "1.23",
+        json_schema: true,
+        intern: true,
+        docs: false,
+        schema_caveat: false,
+    },
+    Release {
+        version: "v1.3.0",
+        message: "Add a README and CHANGELOG",
+        time: 1792094400,
+        go: "1.23",
+        json_schema: true,
+        intern: true,
+        docs: true,
+        schema_caveat: false,
+    },
+    Release {
+        version: "v1.4.0",
+        message: "Document that JSONSchema describes tuples encoded as arrays, which encoding/json doesn't do",
+        time: 1792108800,
+        go: "1.23",
+        json_schema: true,
+        intern: true,
+        docs: true,
+        schema_caveat: true,
+    },
+];
+
+impl Release {
+    pub fn major(&self) -> u64 {
+        self.version[1..]
+            .split('.')
+            .next()
+            .and_then(|m| m.parse().ok())
+            .expect("release versions are of the form vX.Y.Z")
+    }
+
+    pub fn module_path(&self, n: u64) -> String {
+        module_path(n, self.major())
+    }
+}
+
+pub fn latest_major() -> u64 {
+    latest_major_of(RELEASES)
+}
+
+fn latest_major_of(releases: &[Release]) -> u64 {
+    releases.iter().map(Release::major).max().unwrap_or(1)
+}
+
+// module_path returns the go module path for the given arity and major version, following
+// semantic import versioning: v0 and v1 have no suffix, v2 and later get a '/vN' suffix.
+pub fn module_path(n: u64, major: u64) -> String {
+    if major < 2 {
+        format!("pkg.golang.fail/tuple/{}/tuple", n)
+    } else {
+        format!("pkg.golang.fail/tuple/{}/tuple/v{}", n, major)
+    }
+}
+
+// parse_major parses a '/vN' module path suffix, such as "v2", returning the major version if
+// it's one we've released.
+pub fn parse_major(s: &str) -> Option<u64> {
+    parse_major_of(RELEASES, s)
+}
+
+fn parse_major_of(releases: &[Release], s: &str) -> Option<u64> {
+    let major: u64 = s.strip_prefix('v')?.parse().ok()?;
+    if major < 2 || major > latest_major_of(releases) || s != format!("v{}", major) {
+        return None;
+    }
+    Some(major)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+// files returns the name and contents of every file in the generated module for the given
+// release.
+pub fn files(release: &Release, n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    let mut files = vec![
+        (
+            "go.mod",
+            format!(
+                r#"module {}
+
+go {}"#,
+                release.module_path(n),
+                release.go
+            )
+            .into_bytes(),
+        ),
+        ("tuple.go", tuple_go(release, n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ];
+    if release.docs {
+        files.push(("README.md", readme(release, n).into_bytes()));
+        files.push(("CHANGELOG.md", changelog(release).into_bytes()));
+    }
+    files
+}
+
+fn tuple_go(release: &Release, n: u64) -> String {
+    let constraints = if n == 0 {
+        "".into()
+    } else {
+        format!(
+            "[{} any]",
+            (0..n)
+                .map(|i| format!("T{}", i))
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    };
+
+    let tuple_members = if n == 0 {
+        "".into()
+    } else {
+        (0..n)
+            .map(|i| format!("T{i} T{i}"))
+            .collect::<Vec<_>>()
+            .join("\n\t")
+    };
+
+    let inst_params = if n == 0 {
+        "".into()
+    } else {
+        format!(
+            "[{}]",
+            (0..n)
+                .map(|i| format!("T{}", i))
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    };
+
+    let multiple_ret = if n == 0 {
+        "".into()
+    } else {
+        format!(
+            "({}) ",
+            (0..n)
+                .map(|i| format!("T{}", i))
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    };
+
+    let multiple_ret_body = if n == 0 {
+        "".into()
+    } else {
+        format!(
+            "return {}",
+            (0..n)
+                .map(|i| format!("t.T{}", i))
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    };
+
+    let new_args = (0..n)
+        .map(|i| format!("t{i} T{i}"))
+        .collect::<Vec<_>>()
+        .join(", ");
+
+    let struct_inst_body = (0..n)
+        .map(|i| format!("t{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+
+    let imports = if release.intern {
+        "\nimport \"unique\"\n"
+    } else {
+        ""
+    };
+
+    let json_schema = if release.json_schema {
+        json_schema(release, n)
+    } else {
+        "".into()
+    };
+
+    let intern = if release.intern {
+        intern(n, &inst_params)
+    } else {
+        "".into()
+    };
+
+    format!(
+        r#"// Package tuple provides an {n}-ary tuple implementation. This is useful for cases
+// where multiple values should be grouped together, such as for passing across channels.
+// Multiple returns and n-ary tuples may be converted between as well by using the 'New' method to
+// go from n-ary return to a Tuple, and using Tuple.Unpack to convert in the other direction.
+package tuple
+{imports}
+// Tuple implements an {n}-ary generic tuple.
+// It may be used
+type Tuple{constraints} struct {{
+	{tuple_members}
+}}
+
+// New constructs a new tuple from the given arguments.
+func New{constraints}({new_args}) Tuple{inst_params} {{
+	return Tuple{inst_params}{{{struct_inst_body}}}
+}}
+
+// Unpack unpacks a Tuple via multiple-return of the contained data.
+func (t Tuple{inst_params}) Unpack() {multiple_ret}{{
+	{multiple_ret_body}
+}}
+{json_schema}{intern}"#
+    )
+}
+
+fn json_schema(release: &Release, n: u64) -> String {
+    let schema_args = (0..n)
+        .map(|i| format!("s{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let schema_params = if n == 0 {
+        "".into()
+    } else {
+        format!("{} any", schema_args)
+    };
+    let caveat = if release.schema_caveat {
+        let fields = (0..n)
+            .map(|i| format!("t.T{}", i))
+            .collect::<Vec<_>>()
+            .join(", ");
+        format!(
+            r#"//
+// Tuple has no MarshalJSON method, so encoding/json encodes it as an object with a field per
+// element instead. The schema only applies to tuples the caller encodes as arrays itself, such as by
+// encoding []any{{{fields}}}.
+"#
+        )
+    } else {
+        "".into()
+    };
+
+    format!(
+        r#"
+// JSONSchema returns a JSON Schema (draft 2020-12) describing a Tuple encoded as a JSON array of its
+// {n} elements, in order, where the elements are described by the given schemas. Each schema may be
+// anything that encodes to a JSON Schema, such as a map[string]any or a json.RawMessage.
+{caveat}func JSONSchema({schema_params}) map[string]any {{
+	return map[string]any{{
+		"type":        "array",
+		"prefixItems": []any{{{schema_args}}},
+		"items":       false,
+		"minItems":    {n},
+	}}
+}}
+"#
+    )
+}
+
+fn intern(n: u64, inst_params: &str) -> String {
+    let constraints = if n == 0 {
+        "".into()
+    } else {
+        format!(
+            "[{} comparable]",
+            (0..n)
+                .map(|i| format!("T{}", i))
+                .collect::<Vec<_>>()
+                .join(", ")
+        )
+    };
+
+    format!(
+        r#"
+// Handle is a handle to an interned tuple. Two handles are equal if and only if the tuples they
+// were interned from are equal, and comparing them is as cheap as comparing pointers.
+type Handle{constraints} struct {{
+	h unique.Handle[Tuple{inst_params}]
+}}
+
+// Intern returns a handle to a canonical copy of t. Interning many equal tuples keeps only one copy
+// of them in memory, for as long as any of their handles are reachable.
+func Intern{constraints}(t Tuple{inst_params}) Handle{inst_params} {{
+	return Handle{inst_params}{{unique.Make(t)}}
+}}
+
+// Value returns a copy of the interned tuple.
+func (h Handle{inst_params}) Value() Tuple{inst_params} {{
+	return h.h.Value()
+}}
+"#
+    )
+}
+
+// readme returns README.md for the n-ary tuple: how to get it, a summary of its API, and an example.
+fn readme(release: &Release, n: u64) -> String {
+    let path = release.module_path(n);
+    let types = (0..n).map(|i| format!("T{}", i)).collect::<Vec<_>>();
+    let (constraints, inst_params, comparable) = if n == 0 {
+        ("".to_string(), "".to_string(), "".to_string())
+    } else {
+        (
+            format!("[{} any]", types.join(", ")),
+            format!("[{}]", types.join(", ")),
+            format!("[{} comparable]", types.join(", ")),
+        )
+    };
+    let (count, fields) = match n {
+        0 => ("no elements".to_string(), "".to_string()),
+        1 => (
+            "1 element".to_string(),
+            ", `T0`, in a field of the same name".to_string(),
+        ),
+        _ => (
+            format!("{} elements", n),
+            format!(", `T0` to `T{}`, in fields of the same names", n - 1),
+        ),
+    };
+    let results = match n {
+        0 => "".to_string(),
+        1 => " T0".to_string(),
+        _ => format!(" ({})", types.join(", ")),
+    };
+
+    let mut api = vec![
+        format!("- `type Tuple{}` holds {}{}.", constraints, count, fields),
+        format!(
+            "- `func New{}({}) Tuple{}` returns a tuple of its arguments.",
+            constraints,
+            (0..n)
+                .map(|i| format!("t{i} T{i}"))
+                .collect::<Vec<_>>()
+                .join(", "),
+            inst_params
+        ),
+        format!(
+            "- `func (t Tuple{}) Unpack(){}` returns the elements, for multiple assignment.",
+            inst_params, results
+        ),
+    ];
+    if release.json_schema {
+        api.push(format!(
+            "- `func JSONSchema({}) map[string]any` returns a JSON Schema for a tuple encoded as a JSON array of its elements, given schemas for them.{}",
+            match n {
+                0 => "".to_string(),
+                _ => format!(
+                    "{} any",
+                    (0..n)
+                        .map(|i| format!("s{}", i))
+                        .collect::<Vec<_>>()
+                        .join(", ")
+                ),
+            },
+            if release.schema_caveat {
+                " encoding/json encodes tuples as objects with a field per element instead, so it only applies to tuples you encode as arrays yourself."
+            } else {
+                ""
+            }
+        ));
+    }
+    if release.intern {
+        api.push(format!(
+            "- `func Intern{}(t Tuple{}) Handle{}` interns comparable tuples, and `Handle.Value` returns them.",
+            comparable, inst_params, inst_params
+        ));
+    }
+
+    format!(
+        r#"# {n}-ary tuple
+
+`{path}` provides a generic tuple of {count}, for grouping values
+together, such as for passing them across channels, or converting multiple return values to a
+single value and back.
+
+It's generated for each arity by [pkg.golang.fail](https://pkg.golang.fail/tuple), so for tuples
+of other sizes, swap {n} in the import path for the number of elements.
+
+## Getting it
+
+```sh
+go get {path}@{version}
+```
+
+```go
+import "{path}"
+```
+
+This version needs go {go} or later.
+
+## API
+
+{api}
+
+## Example
+
+```go
+{example}```
+
+## License
+
+BSD 3-Clause, see [LICENSE](LICENSE). See [CHANGELOG.md](CHANGELOG.md) for what changed in each
+release.
+"#,
+        version = release.version,
+        go = release.go,
+        api = api.join("\n"),
+        example = readme_example(release, n),
+    )
+}
+
+// readme_example returns a program using the n-ary tuple, with its output in comments.
+fn readme_example(release: &Release, n: u64) -> String {
+    // a mix of types, so the example shows off the tuple's generics
+    let values = (0..n)
+        .map(|i| match i % 3 {
+            0 => (i.to_string(), i.to_string()),
+            1 => (format!("\"t{}\"", i), format!("t{}", i)),
+            _ => (format!("{}.5", i), format!("{}.5", i)),
+        })
+        .collect::<Vec<_>>();
+    let args = values
+        .iter()
+        .map(|(arg, _)| arg.as_str())
+        .collect::<Vec<_>>()
+        .join(", ");
+    let printed = values
+        .iter()
+        .map(|(_, printed)| printed.as_str())
+        .collect::<Vec<_>>()
+        .join(" ");
+
+    let print = if n == 0 {
+        "\tfmt.Println(t) // {}\n".to_string()
+    } else {
+        format!("\tfmt.Println(t.Unpack()) // {}\n", printed)
+    };
+    format!(
+        "package main\n\nimport (\n\t\"fmt\"\n\n\ttuple \"{}\"\n)\n\nfunc main() {{\n\tt := tuple.New({})\n{}}}\n",
+        release.module_path(n),
+        args,
+        print
+    )
+}
+
+// changelog returns CHANGELOG.md for a release, listing it and every release before it, newest
+// first.
+fn changelog(release: &Release) -> String {
+    let releases = RELEASES
+        .iter()
+        .take_while(|r| r.version != release.version)
+        .chain([release])
+        .collect::<Vec<_>>();
+    let entries = releases
+        .iter()
+        .enumerate()
+        .rev()
+        .map(|(i, r)| {
+            let mut notes = Vec::new();
+            if i > 0 && r.major() != releases[i - 1].major() {
+                notes.push(format!(
+                    "This is a breaking change, so the module path is now `{}`, where `$NUM` is the arity.",
+                    module_path(0, r.major()).replace("/0/", "/$NUM/")
+                ));
+            }
+            if i > 0 && r.go != releases[i - 1].go {
+                notes.push(format!("This release needs go {} or later.", r.go));
+            }
+            let notes = notes
+                .iter()
+                .map(|note| format!(" {}", note))
+                .collect::<String>();
+            format!(
+                "## {} ({})\n\n{}.{}\n",
+                r.version,
+                &crate::api::rfc3339(r.time)[..10],
+                r.message,
+                notes
+            )
+        })
+        .collect::<Vec<_>>();
+    format!("# Changelog\n\n{}", entries.join("\n"))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    // a breaking release, since there hasn't been one yet
+    const WITH_V2: &[Release] = &[
+        Release {
+            version: "v1.0.0",
+            message: "Initial commit",
+            time: 420,
+            go: "1.18",
+            json_schema: false,
+            intern: false,
+            docs: false,
+            schema_caveat: false,
+        },
+        Release {
+            version: "v2.0.0",
+            message: "Rename Unpack to Values",
+            time: 840,
+            go: "1.18",
+            json_schema: false,
+            intern: false,
+            docs: false,
+            schema_caveat: false,
+        },
+    ];
+
+    fn go_mod(release: &Release, n: u64) -> String {
+        let (_, contents) = files(release, n)
+            .into_iter()
+            .find(|(name, _)| *name == "go.mod")
+            .unwrap();
+        String::from_utf8(contents).unwrap()
+    }
+
+    #[test]
+    fn module_paths() {
+        assert_eq!(module_path(3, 0), "pkg.golang.fail/tuple/3/tuple");
+        assert_eq!(module_path(3, 1), "pkg.golang.fail/tuple/3/tuple");
+        assert_eq!(module_path(3, 2), "pkg.golang.fail/tuple/3/tuple/v2");
+        assert_eq!(
+            WITH_V2[1].module_path(0),
+            "pkg.golang.fail/tuple/0/tuple/v2"
+        );
+    }
+
+    #[test]
+    fn go_mod_module_lines() {
+        assert_eq!(
+            go_mod(&WITH_V2[0], 3),
+            "module pkg.golang.fail/tuple/3/tuple\n\ngo 1.18"
+        );
+        assert_eq!(
+            go_mod(&WITH_V2[1], 3),
+            "module pkg.golang.fail/tuple/3/tuple/v2\n\ngo 1.18"
+        );
+    }
+
+    fn file(release: &Release, n: u64, name: &str) -> String {
+        let (_, contents) = files(release, n)
+            .into_iter()
+            .find(|(file, _)| *file == name)
+            .unwrap();
+        String::from_utf8(contents).unwrap()
+    }
+
+    #[test]
+    fn files_of_each_release() {
+        for release in RELEASES {
+            let names = files(release, 2)
+                .into_iter()
+                .map(|(name, _)| name)
+                .collect::<Vec<_>>();
+            let mut want = vec!["go.mod", "tuple.go", "LICENSE"];
+            if release.docs {
+                want.extend(["README.md", "CHANGELOG.md"]);
+            }
+            assert_eq!(names, want, "{}", release.version);
+
+            assert!(go_mod(release, 2).ends_with(&format!("\n\ngo {}", release.go)));
+            let tuple_go = file(release, 2, "tuple.go");
+            assert_eq!(
+                tuple_go.contains("func JSONSchema("),
+                release.json_schema,
+                "{}",
+                release.version
+            );
+            assert_eq!(
+                tuple_go.contains("func Intern["),
+                release.intern,
+                "{}",
+                release.version
+            );
+            assert_eq!(
+                tuple_go.contains("Tuple has no MarshalJSON method"),
+                release.schema_caveat,
+                "{}",
+                release.version
+            );
+        }
+    }
+
+    #[test]
+    fn initial_release_is_unchanged() {
+        // its go.sum hash is in the wild
+        assert_eq!(
+            file(&RELEASES[0], 2, "tuple.go"),
+            r##"// Package tuple provides an 2-ary tuple implementation. This is useful for cases
+// where multiple values should be grouped together, such as for passing across channels.
+// Multiple returns and n-ary tuples may be converted between as well by using the 'New' method to
+// go from n-ary return to a Tuple, and using Tuple.Unpack to convert in the other direction.
+package tuple
+
+// Tuple implements an 2-ary generic tuple.
+// It may be used
+type Tuple[T0, T1 any] struct {
+	T0 T0
+	T1 T1
+}
+
+// New constructs a new tuple from the given arguments.
+func New[T0, T1 any](t0 T0, t1 T1) Tuple[T0, T1] {
+	return Tuple[T0, T1]{t0, t1}
+}
+
+// Unpack unpacks a Tuple via multiple-return of the contained data.
+func (t Tuple[T0, T1]) Unpack() (T0, T1) {
+	return t.T0, t.T1
+}
+"##
+        );
+    }
+
+    #[test]
+    fn parse_majors() {
+        assert_eq!(parse_major_of(WITH_V2, "v2"), Some(2));
+        for s in ["v0", "v1", "v3", "v02", "2", "v", "v2.0.0"] {
+            assert_eq!(parse_major_of(WITH_V2, s), None, "{}", s);
+        }
+        // every release so far is v1
+        assert_eq!(latest_major(), 1);
+        assert_eq!(parse_major("v2"), None);
+    }
+
+    // v1_3_0 is the first release with docs, whose README and CHANGELOG can never change.
+    fn v1_3_0() -> &'static Release {
+        RELEASES.iter().find(|r| r.version == "v1.3.0").unwrap()
+    }
+
+    fn doc(n: u64, name: &str) -> String {
+        file(v1_3_0(), n, name)
+    }
+
+    #[test]
+    fn readmes() {
+        assert_eq!(
+            doc(0, "README.md"),
+            r##"# 0-ary tuple
+
+`pkg.golang.fail/tuple/0/tuple` provides a generic tuple of no elements, for grouping values
+together, such as for passing them across channels, or converting multiple return values to a
+single value and back.
+
+It's generated for each arity by [pkg.golang.fail](https://pkg.golang.fail/tuple), so for tuples
+of other sizes, swap 0 in the import path for the number of elements.
+
+## Getting it
+
+```sh
+go get pkg.golang.fail/tuple/0/tuple@v1.3.0
+```
+
+```go
+import "pkg.golang.fail/tuple/0/tuple"
+```
+
+This version needs go 1.23 or later.
+
+## API
+
+- `type Tuple` holds no elements.
+- `func New() Tuple` returns a tuple of its arguments.
+- `func (t Tuple) Unpack()` returns the elements, for multiple assignment.
+- `func JSONSchema() map[string]any` returns a JSON Schema for a tuple encoded as a JSON array of its elements, given schemas for them.
+- `func Intern(t Tuple) Handle` interns comparable tuples, and `Handle.Value` returns them.
+
+## Example
+
+```go
+package main
+
+import (
+	"fmt"
+
+	tuple "pkg.golang.fail/tuple/0/tuple"
+)
+
+func main() {
+	t := tuple.New()
+	fmt.Println(t) // {}
+}
+```
+
+## License
+
+BSD 3-Clause, see [LICENSE](LICENSE). See [CHANGELOG.md](CHANGELOG.md) for what changed in each
+release.
+"##
+        );
+        assert_eq!(
+            doc(1, "README.md"),
+            r##"# 1-ary tuple
+
+`pkg.golang.fail/tuple/1/tuple` provides a generic tuple of 1 element, for grouping values
+together, such as for passing them across channels, or converting multiple return values to a
+single value and back.
+
+It's generated for each arity by [pkg.golang.fail](https://pkg.golang.fail/tuple), so for tuples
+of other sizes, swap 1 in the import path for the number of elements.
+
+## Getting it
+
+```sh
+go get pkg.golang.fail/tuple/1/tuple@v1.3.0
+```
+
+```go
+import "pkg.golang.fail/tuple/1/tuple"
+```
+
+This version needs go 1.23 or later.
+
+## API
+
+- `type Tuple[T0 any]` holds 1 element, `T0`, in a field of the same name.
+- `func New[T0 any](t0 T0) Tuple[T0]` returns a tuple of its arguments.
+- `func (t Tuple[T0]) Unpack() T0` returns the elements, for multiple assignment.
+- `func JSONSchema(s0 any) map[string]any` returns a JSON Schema for a tuple encoded as a JSON array of its elements, given schemas for them.
+- `func Intern[T0 comparable](t Tuple[T0]) Handle[T0]` interns comparable tuples, and `Handle.Value` returns them.
+
+## Example
+
+```go
+package main
+
+import (
+	"fmt"
+
+	tuple "pkg.golang.fail/tuple/1/tuple"
+)
+
+func main() {
+	t := tuple.New(0)
+	fmt.Println(t.Unpack()) // 0
+}
+```
+
+## License
+
+BSD 3-Clause, see [LICENSE](LICENSE). See [CHANGELOG.md](CHANGELOG.md) for what changed in each
+release.
+"##
+        );
+        assert_eq!(
+            doc(3, "README.md"),
+            r##"# 3-ary tuple
+
+`pkg.golang.fail/tuple/3/tuple` provides a generic tuple of 3 elements, for grouping values
+together, such as for passing them across channels, or converting multiple return values to a
+single value and back.
+
+It's generated for each arity by [pkg.golang.fail](https://pkg.golang.fail/tuple), so for tuples
+of other sizes, swap 3 in the import path for the number of elements.
+
+## Getting it
+
+```sh
+go get pkg.golang.fail/tuple/3/tuple@v1.3.0
+```
+
+```go
+import "pkg.golang.fail/tuple/3/tuple"
+```
+
+This version needs go 1.23 or later.
+
+## API
+
+- `type Tuple[T0, T1, T2 any]` holds 3 elements, `T0` to `T2`, in fields of the same names.
+- `func New[T0, T1, T2 any](t0 T0, t1 T1, t2 T2) Tuple[T0, T1, T2]` returns a tuple of its arguments.
+- `func (t Tuple[T0, T1, T2]) Unpack() (T0, T1, T2)` returns the elements, for multiple assignment.
+- `func JSONSchema(s0, s1, s2 any) map[string]any` returns a JSON Schema for a tuple encoded as a JSON array of its elements, given schemas for them.
+- `func Intern[T0, T1, T2 comparable](t Tuple[T0, T1, T2]) Handle[T0, T1, T2]` interns comparable tuples, and `Handle.Value` returns them.
+
+## Example
+
+```go
+package main
+
+import (
+	"fmt"
+
+	tuple "pkg.golang.fail/tuple/3/tuple"
+)
+
+func main() {
+	t := tuple.New(0, "t1", 2.5)
+	fmt.Println(t.Unpack()) // 0 t1 2.5
+}
+```
+
+## License
+
+BSD 3-Clause, see [LICENSE](LICENSE). See [CHANGELOG.md](CHANGELOG.md) for what changed in each
+release.
+"##
+        );
+    }
+
+    #[test]
+    fn changelogs() {
+        for n in [0, 1, 3] {
+            assert_eq!(
+                doc(n, "CHANGELOG.md"),
+                r##"# Changelog
+
+## v1.3.0 (2026-10-15)
+
+Add a README and CHANGELOG.
+
+## v1.2.0 (2026-10-15)
+
+Add Intern, interning comparable tuples with the unique package. This release needs go 1.23 or later.
+
+## v1.1.0 (2026-10-14)
+
+Add JSONSchema, describing a tuple encoded as a JSON array.
+
+## v1.0.0 (1970-01-01)
+
+Initial commit.
+"##
+            );
+        }
+        // releases before docs were added don't have them
+        assert!(files(&RELEASES[0], 3)
+            .iter()
+            .all(|(name, _)| *name != "README.md" && *name != "CHANGELOG.md"));
+    }
+}
@@ -0,0 +1,348 @@
+// The units module templates: for each dimension, given as exponents of metres, kilograms and
+// seconds, a module with a float64 Quantity type of that dimension, and for each pair of
+// dimensions, modules multiplying and dividing quantities of those dimensions into quantities of
+// the resulting dimension.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1792065600;
+
+// Dimension is the exponents of metres, kilograms and seconds, in the order they appear in module
+// paths.
+pub type Dimension = [i64; 3];
+
+#[derive(Clone, Copy)]
+pub enum Op {
+    Mul,
+    Div,
+}
+
+impl Op {
+    pub fn name(self) -> &'static str {
+        match self {
+            Op::Mul => "mul",
+            Op::Div => "div",
+        }
+    }
+
+    fn result(self, a: Dimension, b: Dimension) -> Dimension {
+        let sign = match self {
+            Op::Mul => 1,
+            Op::Div => -1,
+        };
+        [a[0] + sign * b[0], a[1] + sign * b[1], a[2] + sign * b[2]]
+    }
+}
+
+// format_exponent spells an exponent for a module path. Negative exponents start with an 'n'
+// rather than a '-', such as 'n2' for -2, so that no path element starts with '-', which tools
+// handed a path may take for a flag.
+pub fn format_exponent(e: i64) -> String {
+    if e < 0 {
+        format!("n{}", e.unsigned_abs())
+    } else {
+        e.to_string()
+    }
+}
+
+// parse_exponent parses an exponent spelled as format_exponent does, accepting only its canonical
+// spelling, and exponents small enough that their sums and differences fit in an i64.
+pub fn parse_exponent(s: &str) -> Option<i64> {
+    let (negative, digits) = match s.strip_prefix('n') {
+        Some(digits) => (true, digits),
+        None => (false, s),
+    };
+    let e = digits.parse::<u32>().ok()?;
+    if digits != e.to_string() || (negative && e == 0) {
+        return None;
+    }
+    let e = if negative {
+        -i64::from(e)
+    } else {
+        i64::from(e)
+    };
+    i32::try_from(e).ok().map(i64::from)
+}
+
+// dimension_path is the part of a module path giving a dimension, such as '1/0/n2'.
+fn dimension_path(d: Dimension) -> String {
+    d.map(format_exponent).join("/")
+}
+
+// module_path ends with the package name, rather than the last exponent, since the go command
+// insists on packages' last path element starting with a letter or digit.
+pub fn module_path(d: Dimension) -> String {
+    format!("pkg.golang.fail/units/{}/units", dimension_path(d))
+}
+
+// op_module_path ends with the package name, like module_path.
+pub fn op_module_path(op: Op, a: Dimension, b: Dimension) -> String {
+    format!(
+        "pkg.golang.fail/units/{}/{op}/{}/{op}",
+        dimension_path(a),
+        dimension_path(b),
+        op = op.name()
+    )
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(d: Dimension) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!("module {}\n\ngo 1.18\n", module_path(d)).into_bytes(),
+        ),
+        ("units.go", units_go(d).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+pub fn op_files(op: Op, a: Dimension, b: Dimension) -> Vec<(&'static str, Vec<u8>)> {
+    let mut requires = vec![module_path(a), module_path(b), module_path(op.result(a, b))];
+    requires.sort();
+    requires.dedup();
+    let requires = requires
+        .iter()
+        .map(|path| format!("\t{} {}\n", path, VERSION))
+        .collect::<String>();
+
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.18\n\nrequire (\n{})\n",
+                op_module_path(op, a, b),
+                requires
+            )
+            .into_bytes(),
+        ),
+        (
+            match op {
+                Op::Mul => "mul.go",
+                Op::Div => "div.go",
+            },
+            op_go(op, a, b).into_bytes(),
+        ),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+// symbol returns the unit of a dimension in terms of base units, such as "kg·m·s⁻²", or "" for
+// dimensionless quantities.
+fn symbol(d: Dimension) -> String {
+    // kilograms first, as in the usual spelling of derived units such as the newton
+    [("kg", d[1]), ("m", d[0]), ("s", d[2])]
+        .iter()
+        .filter(|(_, e)| *e != 0)
+        .map(|(unit, e)| match e {
+            1 => unit.to_string(),
+            e => format!("{}{}", unit, superscript(*e)),
+        })
+        .collect::<Vec<_>>()
+        .join("·")
+}
+
+fn superscript(e: i64) -> String {
+    e.to_string()
+        .chars()
+        .map(|c| match c {
+            '-' => '⁻',
+            '0' => '⁰',
+            '1' => '¹',
+            '2' => '²',
+            '3' => '³',
+            '4' => '⁴',
+            '5' => '⁵',
+            '6' => '⁶',
+            '7' => '⁷',
+            '8' => '⁸',
+            _ => '⁹',
+        })
+        .collect()
+}
+
+// describe returns how docs refer to quantities of a dimension.
+fn describe(d: Dimension) -> String {
+    match symbol(d).as_str() {
+        "" => "dimensionless quantities".to_string(),
+        symbol => format!("quantities in {}", symbol),
+    }
+}
+
+fn units_go(d: Dimension) -> String {
+    let symbol = symbol(d);
+    let (quantity_doc, unit_doc, string_doc, string) = if symbol.is_empty() {
+        (
+            "Quantity is a dimensionless float64.".to_string(),
+            "Unit is one, since Quantity is dimensionless.".to_string(),
+            "String formats q, such as \"0.5\"".to_string(),
+            "strconv.FormatFloat(float64(q), 'g', -1, 64)".to_string(),
+        )
+    } else {
+        (
+            format!("Quantity is a float64 measured in {}.", symbol),
+            format!("Unit is one {}.", symbol),
+            format!(
+                "String formats q with its unit, such as \"9.81 {}\"",
+                symbol
+            ),
+            format!(
+                "strconv.FormatFloat(float64(q), 'g', -1, 64) + \" {}\"",
+                symbol
+            ),
+        )
+    };
+    let (m, kg, s) = (d[0], d[1], d[2]);
+    let path = dimension_path(d);
+
+    format!(
+        r#"// Package units provides Quantity, for {description}.
+//
+// Quantities of the same dimension can be added, subtracted and compared with go's operators,
+// while mixing dimensions doesn't compile. Quantities of this dimension are multiplied and divided
+// by quantities of the dimension with exponents $M, $KG and $S by the modules
+//
+//	{mul_path}
+//	{div_path}
+//
+// where negative exponents are spelled with an n, such as n2 for -2.
+package units
+
+import "strconv"
+
+// {quantity_doc}
+type Quantity float64
+
+// {unit_doc}
+const Unit Quantity = 1
+
+// The exponents of metres, kilograms and seconds in the dimension of Quantity.
+const (
+	M  = {m}
+	KG = {kg}
+	S  = {s}
+)
+
+// Symbol is the unit of Quantity in terms of base units, or "" if Quantity is dimensionless.
+const Symbol = "{symbol}"
+
+// Scale returns q multiplied by a dimensionless factor.
+func (q Quantity) Scale(f float64) Quantity {{
+	return Quantity(float64(q) * f)
+}}
+
+// {string_doc}.
+func (q Quantity) String() string {{
+	return {string}
+}}
+"#,
+        description = describe(d),
+        mul_path = format!("pkg.golang.fail/units/{}/mul/$M/$KG/$S/mul", path),
+        div_path = format!("pkg.golang.fail/units/{}/div/$M/$KG/$S/div", path),
+    )
+}
+
+fn op_go(op: Op, a: Dimension, b: Dimension) -> String {
+    let c = op.result(a, b);
+    // operands and results of the same dimension share an import
+    let mut imports: Vec<(&str, String)> = Vec::new();
+    let mut alias = |name: &'static str, d: Dimension| {
+        let path = module_path(d);
+        match imports.iter().find(|(_, p)| *p == path) {
+            Some((existing, _)) => existing.to_string(),
+            None => {
+                imports.push((name, path));
+                name.to_string()
+            }
+        }
+    };
+    let (x, y, result) = (alias("lhs", a), alias("rhs", b), alias("result", c));
+    // sorted by path, as gofmt would
+    imports.sort_by(|i, j| i.1.cmp(&j.1));
+    let imports = imports
+        .iter()
+        .map(|(name, path)| format!("\t{} \"{}\"\n", name, path))
+        .collect::<String>();
+
+    let (doc, func, operator) = match op {
+        Op::Mul => (
+            format!(
+                "multiplies {} by {}, giving {}",
+                describe(a),
+                describe(b),
+                describe(c)
+            ),
+            "Mul",
+            "*",
+        ),
+        Op::Div => (
+            format!(
+                "divides {} by {}, giving {}",
+                describe(a),
+                describe(b),
+                describe(c)
+            ),
+            "Div",
+            "/",
+        ),
+    };
+
+    format!(
+        r#"// Package {name} {doc}.
+package {name}
+
+import (
+{imports})
+
+// {func} returns x{operator}y.
+func {func}(x {x}.Quantity, y {y}.Quantity) {result}.Quantity {{
+	return {result}.Quantity(float64(x) {operator} float64(y))
+}}
+"#,
+        name = op.name(),
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn spells_negative_exponents_with_an_n() {
+        assert_eq!(
+            module_path([1, 0, -2]),
+            "pkg.golang.fail/units/1/0/n2/units"
+        );
+        assert_eq!(
+            op_module_path(Op::Div, [1, 0, 0], [0, 0, 1]),
+            "pkg.golang.fail/units/1/0/0/div/0/0/1/div"
+        );
+        assert_eq!(
+            op_module_path(Op::Mul, [0, -1, 0], [-10, 0, 3]),
+            "pkg.golang.fail/units/0/n1/0/mul/n10/0/3/mul"
+        );
+    }
+
+    #[test]
+    fn parses_exponents() {
+        for e in [0, 1, -1, 12, -12, i32::MAX.into(), i32::MIN.into()] {
+            assert_eq!(parse_exponent(&format_exponent(e)), Some(e));
+        }
+        for s in [
+            "",
+            "n",
+            "-2",
+            "+2",
+            "n0",
+            "02",
+            "n02",
+            "nn2",
+            "2n",
+            "2147483648",
+            "n2147483649",
+        ] {
+            assert_eq!(parse_exponent(s), None, "{:?}", s);
+        }
+    }
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	// quantities in seconds, kilograms, metres per second (squared), and metres
+	s "pkg.golang.fail/units/0/0/1/units"
+	kg "pkg.golang.fail/units/0/1/0/units"
+	mps "pkg.golang.fail/units/1/0/n1/units"
+	mps2 "pkg.golang.fail/units/1/0/n2/units"
+	m "pkg.golang.fail/units/1/0/0/units"
+
+	// multiplying and dividing quantities of one dimension by quantities of another
+	force "pkg.golang.fail/units/0/1/0/mul/1/0/n2/mul"
+	accel "pkg.golang.fail/units/1/0/n1/div/0/0/1/div"
+	speed "pkg.golang.fail/units/1/0/0/div/0/0/1/div"
+)
+
+func main() {
+	distance := 100 * m.Unit
+	time := 9.58 * s.Unit
+
+	var v mps.Quantity = speed.Div(distance, time)
+	fmt.Println(v) // 10.438413361169102 m·s⁻¹
+
+	var a mps2.Quantity = accel.Div(v, time)
+	fmt.Println(force.Mul(94*kg.Unit, a)) // 102.4228450887156 kg·m·s⁻²
+
+	// quantities of the same dimension add up as usual, but mixing dimensions doesn't compile:
+	//   distance + time // invalid operation: mismatched types
+	fmt.Println(distance+distance.Scale(0.5), distance > 50*m.Unit) // 150 m true
+}
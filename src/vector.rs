@@ -0,0 +1,108 @@
+// The n-dimensional vector module template. Vectors are mostly here as the rows and columns of
+// matrices, and to convert between those and tuples.
+
+pub const VERSION: &str = "v1.0.0";
+// commit time, fixed so generated repos are reproducible
+pub const TIME: i64 = 1791957600;
+
+// the tuple release whose module vectors convert to and from; pinned, since released vector
+// modules can never change
+pub const TUPLE_VERSION: &str = "v1.1.0";
+
+pub fn module_path(n: u64) -> String {
+    format!("pkg.golang.fail/vector/{}", n)
+}
+
+const BSD3_LICENSE: &[u8] = include_bytes!("./BSD3_LICENSE");
+
+pub fn files(n: u64) -> Vec<(&'static str, Vec<u8>)> {
+    vec![
+        (
+            "go.mod",
+            format!(
+                "module {}\n\ngo 1.18\n\nrequire {} {}\n",
+                module_path(n),
+                crate::tuple::module_path(n, 1),
+                TUPLE_VERSION
+            )
+            .into_bytes(),
+        ),
+        ("vector.go", vector_go(n).into_bytes()),
+        ("LICENSE", BSD3_LICENSE.to_vec()),
+    ]
+}
+
+fn vector_go(n: u64) -> String {
+    let tuple_path = crate::tuple::module_path(n, 1);
+    let tuple_params = (0..n).map(|_| "T").collect::<Vec<_>>().join(", ");
+    let elems = (0..n)
+        .map(|i| format!("v[{}]", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+    let tuple_elems = (0..n)
+        .map(|i| format!("t.T{}", i))
+        .collect::<Vec<_>>()
+        .join(", ");
+
+    format!(
+        r#"// Package vector provides a {n}-dimensional vector type.
+package vector
+
+import "{tuple_path}"
+
+// Number is the set of types vectors may contain.
+type Number interface {{
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~complex64 | ~complex128
+}}
+
+// Vec is a {n}-dimensional vector.
+type Vec[T Number] [{n}]T
+
+// FromTuple converts a tuple of {n} values to a vector.
+func FromTuple[T Number](t tuple.Tuple[{tuple_params}]) Vec[T] {{
+	return Vec[T]{{{tuple_elems}}}
+}}
+
+// Tuple converts the vector to a tuple of its {n} values.
+func (v Vec[T]) Tuple() tuple.Tuple[{tuple_params}] {{
+	return tuple.New({elems})
+}}
+
+// Add returns the sum of v and o.
+func (v Vec[T]) Add(o Vec[T]) Vec[T] {{
+	for i := range v {{
+		v[i] += o[i]
+	}}
+	return v
+}}
+
+// Sub returns the difference of v and o.
+func (v Vec[T]) Sub(o Vec[T]) Vec[T] {{
+	for i := range v {{
+		v[i] -= o[i]
+	}}
+	return v
+}}
+
+// Scale returns v multiplied by k.
+func (v Vec[T]) Scale(k T) Vec[T] {{
+	for i := range v {{
+		v[i] *= k
+	}}
+	return v
+}}
+
+// Dot returns the dot product of v and o.
+func (v Vec[T]) Dot(o Vec[T]) T {{
+	var sum T
+	for i := range v {{
+		sum += v[i] * o[i]
+	}}
+	return sum
+}}
+"#
+    )
+}
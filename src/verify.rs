@@ -0,0 +1,266 @@
+// The 'verify' subcommand, which checks that running servers (mirrors, replicas) serve exactly what
+// we'd generate ourselves:
+//
+//   pkg-golang-fail verify [--arities 0-8] https://pkg.golang.fail http://localhost:8081 ...
+//
+// For each arity and server, it compares the refs advertised by '/info/refs', the tree of every
+// ref after cloning, and the go.sum ('h1:') hash of every version, from its files as served by
+// '/tuple/:n/tuple/raw/', against our local write_nary_tuple output. Raw files are fetched with
+// curl, which needs to be installed.
+
+use std::path::Path;
+
+use sha2::{Digest, Sha256};
+
+use crate::mirror;
+
+pub fn main(args: &[String]) -> i32 {
+    let mut arities = 0..=8;
+    let mut servers = Vec::new();
+
+    let mut args = args.iter();
+    while let Some(arg) = args.next() {
+        if arg == "--arities" {
+            match args.next().map(String::as_str).and_then(parse_range) {
+                Some(r) => arities = r,
+                None => {
+                    eprintln!("--arities must be a range such as '0-8'");
+                    return 2;
+                }
+            }
+        } else {
+            servers.push(arg.trim_end_matches('/').to_string());
+        }
+    }
+    if servers.is_empty() {
+        eprintln!("usage: pkg-golang-fail verify [--arities 0-8] <server url>...");
+        return 2;
+    }
+
+    let mut mismatches = 0;
+    for n in arities {
+        let local = match local_snapshot(n) {
+            Ok(s) => s,
+            Err(e) => {
+                eprintln!("could not generate tuple/{}: {}", n, e);
+                return 1;
+            }
+        };
+        for server in &servers {
+            let problems = match check(server, n, &local) {
+                Ok(problems) => problems,
+                Err(e) => vec![format!("error: {}", e)],
+            };
+            if problems.is_empty() {
+                println!("ok       {} tuple/{}", server, n);
+            }
+            for problem in &problems {
+                println!("MISMATCH {} tuple/{}: {}", server, n, problem);
+            }
+            mismatches += problems.len();
+        }
+    }
+
+    if mismatches > 0 {
+        println!("{} mismatches", mismatches);
+        return 1;
+    }
+    println!("all servers match");
+    0
+}
+
+fn parse_range(s: &str) -> Option<std::ops::RangeInclusive<u64>> {
+    let (from, to) = s.split_once('-')?;
+    Some(from.parse().ok()?..=to.parse().ok()?)
+}
+
+// Snapshot is everything we compare about a repo, with each list sorted by ref name.
+struct Snapshot {
+    refs: Vec<(String, git2::Oid)>,
+    trees: Vec<(String, git2::Oid)>,
+}
+
+// Local is our own output, to compare servers against.
+struct Local {
+    snapshot: Snapshot,
+    // the files of each tagged version
+    versions: Vec<(String, Vec<(String, Vec<u8>)>)>,
+}
+
+fn local_snapshot(n: u64) -> Result<Local, anyhow::Error> {
+    let tmp = tempfile::TempDir::new()?;
+    crate::write_nary_tuple(tmp.path(), n)?;
+    let repo = git2::Repository::open(tmp.path())?;
+
+    let mut versions = Vec::new();
+    for (name, oid) in mirror::refs(&repo)? {
+        let tag = match name.strip_prefix("refs/tags/") {
+            Some(tag) => tag.to_string(),
+            None => continue,
+        };
+        let mut files = Vec::new();
+        for entry in repo.find_commit(oid)?.tree()?.iter() {
+            let blob = entry.to_object(&repo)?.peel_to_blob()?;
+            let name = entry
+                .name()
+                .ok_or_else(|| anyhow::anyhow!("non-utf8 file name in {}", tag))?;
+            files.push((name.to_string(), blob.content().to_vec()));
+        }
+        versions.push((tag, files));
+    }
+    Ok(Local {
+        snapshot: snapshot(tmp.path())?,
+        versions,
+    })
+}
+
+fn snapshot(root: &Path) -> Result<Snapshot, anyhow::Error> {
+    let repo = git2::Repository::open(root)?;
+    let refs = mirror::refs(&repo)?;
+    let trees = refs
+        .iter()
+        .map(|(name, oid)| Ok((name.clone(), repo.find_commit(*oid)?.tree_id())))
+        .collect::<Result<_, git2::Error>>()?;
+    Ok(Snapshot { refs, trees })
+}
+
+// check returns a description of every way the server's n-ary tuple repo differs from local.
+fn check(server: &str, n: u64, local: &Local) -> Result<Vec<String>, anyhow::Error> {
+    let mut problems = Vec::new();
+
+    let tmp = tempfile::TempDir::new()?;
+    mirror::fetch(server, tmp.path(), &crate::tuple_repo_name(n))?;
+    let repo = git2::Repository::open(tmp.path())?;
+
+    let advertised = advertised_refs(&repo, server, n)?;
+    problems.extend(diff("advertised ref", &local.snapshot.refs, &advertised));
+
+    let cloned = snapshot(tmp.path())?;
+    problems.extend(diff("cloned tree", &local.snapshot.trees, &cloned.trees));
+
+    let mut want = Vec::new();
+    let mut got = Vec::new();
+    for (tag, files) in &local.versions {
+        let module_path = module_path(n, tag)?;
+        want.push((tag.clone(), go_sum_hash(&module_path, tag, files)));
+
+        let mut served = Vec::new();
+        for (name, _) in files {
+            let url = format!(
+                "{}/{}/raw/{}/{}",
+                server,
+                crate::tuple_repo_name(n),
+                tag,
+                name
+            );
+            served.push((name.clone(), get(&url)?));
+        }
+        got.push((tag.clone(), go_sum_hash(&module_path, tag, &served)));
+    }
+    problems.extend(diff("go.sum hash", &want, &got));
+
+    Ok(problems)
+}
+
+// module_path returns the module path of the n-ary tuple as of the release tagged tag.
+fn module_path(n: u64, tag: &str) -> Result<String, anyhow::Error> {
+    crate::tuple::RELEASES
+        .iter()
+        .find(|r| r.version == tag)
+        .map(|r| r.module_path(n))
+        .ok_or_else(|| anyhow::anyhow!("{} is not a release", tag))
+}
+
+// go_sum_hash returns the hash go.sum records for a version of a module with the given files, as
+// computed by golang.org/x/mod/sumdb/dirhash.Hash1.
+fn go_sum_hash(module_path: &str, version: &str, files: &[(String, Vec<u8>)]) -> String {
+    let mut files = files.iter().collect::<Vec<_>>();
+    files.sort_by(|a, b| a.0.cmp(&b.0));
+    let summary = files
+        .iter()
+        .map(|(name, contents)| {
+            format!(
+                "{:x}  {}@{}/{}\n",
+                Sha256::digest(contents),
+                module_path,
+                version,
+                name
+            )
+        })
+        .collect::<String>();
+    format!("h1:{}", base64(&Sha256::digest(summary.as_bytes())))
+}
+
+fn base64(data: &[u8]) -> String {
+    const ALPHABET: &[u8] = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+    let mut out = String::new();
+    for chunk in data.chunks(3) {
+        let bytes = [
+            chunk[0],
+            chunk.get(1).copied().unwrap_or(0),
+            chunk.get(2).copied().unwrap_or(0),
+        ];
+        let bits = u32::from(bytes[0]) << 16 | u32::from(bytes[1]) << 8 | u32::from(bytes[2]);
+        for i in 0..4 {
+            if i <= chunk.len() {
+                out.push(ALPHABET[(bits >> (18 - 6 * i) & 63) as usize] as char);
+            } else {
+                out.push('=');
+            }
+        }
+    }
+    out
+}
+
+// get fetches url with curl, returning the body of a successful response.
+fn get(url: &str) -> Result<Vec<u8>, anyhow::Error> {
+    let output = std::process::Command::new("curl")
+        .args(["--silent", "--show-error", "--fail", "--location"])
+        .arg(url)
+        .stderr(std::process::Stdio::inherit())
+        .output()?;
+    if !output.status.success() {
+        anyhow::bail!("curl {} did not have success: {}", url, output.status);
+    }
+    Ok(output.stdout)
+}
+
+// advertised_refs lists the branches and tags the server advertises from '/info/refs'.
+fn advertised_refs(
+    repo: &git2::Repository,
+    server: &str,
+    n: u64,
+) -> Result<Vec<(String, git2::Oid)>, anyhow::Error> {
+    let mut remote =
+        repo.remote_anonymous(&format!("{}/{}.git", server, crate::tuple_repo_name(n)))?;
+    remote.connect(git2::Direction::Fetch)?;
+    let mut refs = remote
+        .list()?
+        .iter()
+        .filter(|h| h.name().starts_with("refs/heads/") || h.name().starts_with("refs/tags/"))
+        .map(|h| (h.name().to_string(), h.oid()))
+        .collect::<Vec<_>>();
+    refs.sort();
+    Ok(refs)
+}
+
+fn diff<T: PartialEq + std::fmt::Display>(
+    what: &str,
+    want: &[(String, T)],
+    got: &[(String, T)],
+) -> Vec<String> {
+    let mut problems = Vec::new();
+    for (name, w) in want {
+        match got.iter().find(|(n, _)| n == name) {
+            Some((_, g)) if g == w => {}
+            Some((_, g)) => problems.push(format!("{} {}: expected {}, got {}", what, name, w, g)),
+            None => problems.push(format!("{} {}: missing", what, name)),
+        }
+    }
+    for (name, _) in got {
+        if !want.iter().any(|(n, _)| n == name) {
+            problems.push(format!("{} {}: unexpected", what, name));
+        }
+    }
+    problems
+}
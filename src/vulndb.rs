@@ -0,0 +1,382 @@
+// A vulnerability database, in the format govulncheck expects (see
+// https://go.dev/doc/security/vuln/database), describing defective releases of our generated
+// modules. Point govulncheck at it with 'govulncheck -db https://pkg.golang.fail/vulndb'.
+
+use serde::Serialize;
+
+use crate::{family, tuple};
+
+pub struct Advisory {
+    pub id: &'static str,
+    // RFC 3339
+    pub published: &'static str,
+    pub modified: &'static str,
+    pub summary: &'static str,
+    pub details: &'static str,
+    // tuple releases; fixed is None if there isn't a fixed release yet
+    pub introduced: &'static str,
+    pub fixed: Option<&'static str>,
+    pub symbols: &'static [&'static str],
+    // the smallest affected arity, since a defect in code generated per element doesn't affect
+    // tuples without any; every arity from it to family::MAX_ARITY is affected
+    pub min_arity: u64,
+}
+
+// No defective releases have been found so far.
+pub const ADVISORIES: &[Advisory] = &[];
+
+impl Advisory {
+    fn major(&self) -> u64 {
+        tuple::RELEASES
+            .iter()
+            .find(|r| r.version == self.introduced)
+            .expect("advisories must refer to a release")
+            .major()
+    }
+
+    // modules returns the path of every affected module. Every arity is its own module, and the
+    // index has to list each module path explicitly, so they're all listed, whether or not they've
+    // been generated here, since they may have been fetched from elsewhere.
+    fn modules(&self) -> impl Iterator<Item = String> {
+        let major = self.major();
+        (self.min_arity..=family::MAX_ARITY).map(move |n| tuple::module_path(n, major))
+    }
+}
+
+#[derive(Serialize)]
+pub struct DbIndex {
+    modified: &'static str,
+}
+
+pub fn db_index() -> DbIndex {
+    db_index_of(ADVISORIES)
+}
+
+fn db_index_of(advisories: &[Advisory]) -> DbIndex {
+    DbIndex {
+        modified: advisories
+            .iter()
+            .map(|a| a.modified)
+            .max()
+            .unwrap_or("1970-01-01T00:00:00Z"),
+    }
+}
+
+#[derive(Serialize)]
+pub struct ModuleIndex {
+    path: String,
+    vulns: Vec<ModuleVuln>,
+}
+
+#[derive(Serialize)]
+pub struct ModuleVuln {
+    id: &'static str,
+    modified: &'static str,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    fixed: Option<&'static str>,
+}
+
+pub fn modules_index() -> Vec<ModuleIndex> {
+    modules_index_of(ADVISORIES)
+}
+
+fn modules_index_of(advisories: &[Advisory]) -> Vec<ModuleIndex> {
+    let mut modules: Vec<ModuleIndex> = Vec::new();
+    for advisory in advisories {
+        for path in advisory.modules() {
+            let vuln = ModuleVuln {
+                id: advisory.id,
+                modified: advisory.modified,
+                fixed: advisory.fixed.map(semver),
+            };
+            match modules.iter_mut().find(|m| m.path == path) {
+                Some(m) => m.vulns.push(vuln),
+                None => modules.push(ModuleIndex {
+                    path,
+                    vulns: vec![vuln],
+                }),
+            }
+        }
+    }
+    modules
+}
+
+#[derive(Serialize)]
+pub struct VulnIndex {
+    id: &'static str,
+    modified: &'static str,
+    aliases: Vec<&'static str>,
+}
+
+pub fn vulns_index() -> Vec<VulnIndex> {
+    vulns_index_of(ADVISORIES)
+}
+
+fn vulns_index_of(advisories: &[Advisory]) -> Vec<VulnIndex> {
+    advisories
+        .iter()
+        .map(|a| VulnIndex {
+            id: a.id,
+            modified: a.modified,
+            aliases: vec![],
+        })
+        .collect()
+}
+
+#[derive(Serialize)]
+pub struct Entry {
+    schema_version: &'static str,
+    id: &'static str,
+    modified: &'static str,
+    published: &'static str,
+    summary: &'static str,
+    details: &'static str,
+    affected: Vec<Affected>,
+    references: Vec<Reference>,
+}
+
+#[derive(Serialize)]
+pub struct Affected {
+    package: Package,
+    ranges: Vec<Range>,
+    ecosystem_specific: EcosystemSpecific,
+}
+
+#[derive(Serialize)]
+pub struct Package {
+    name: String,
+    ecosystem: &'static str,
+}
+
+#[derive(Serialize)]
+pub struct Range {
+    #[serde(rename = "type")]
+    typ: &'static str,
+    events: Vec<Event>,
+}
+
+#[derive(Serialize, Clone, Copy)]
+#[serde(rename_all = "lowercase")]
+pub enum Event {
+    Introduced(&'static str),
+    Fixed(&'static str),
+}
+
+#[derive(Serialize)]
+pub struct EcosystemSpecific {
+    imports: Vec<Import>,
+}
+
+#[derive(Serialize)]
+pub struct Import {
+    path: String,
+    symbols: &'static [&'static str],
+}
+
+#[derive(Serialize)]
+pub struct Reference {
+    #[serde(rename = "type")]
+    typ: &'static str,
+    url: &'static str,
+}
+
+pub fn entry(id: &str) -> Option<Entry> {
+    entry_of(ADVISORIES, id)
+}
+
+fn entry_of(advisories: &[Advisory], id: &str) -> Option<Entry> {
+    let advisory = advisories.iter().find(|a| a.id == id)?;
+
+    let mut events = vec![Event::Introduced(semver(advisory.introduced))];
+    if let Some(fixed) = advisory.fixed {
+        events.push(Event::Fixed(semver(fixed)));
+    }
+
+    Some(Entry {
+        schema_version: "1.3.1",
+        id: advisory.id,
+        modified: advisory.modified,
+        published: advisory.published,
+        summary: advisory.summary,
+        details: advisory.details,
+        affected: advisory
+            .modules()
+            .map(|path| Affected {
+                package: Package {
+                    name: path.clone(),
+                    ecosystem: "Go",
+                },
+                ranges: vec![Range {
+                    typ: "SEMVER",
+                    events: events.clone(),
+                }],
+                ecosystem_specific: EcosystemSpecific {
+                    imports: vec![Import {
+                        path,
+                        symbols: advisory.symbols,
+                    }],
+                },
+            })
+            .collect(),
+        references: vec![Reference {
+            typ: "WEB",
+            url: "https://pkg.golang.fail/tuple",
+        }],
+    })
+}
+
+// the go vulndb uses semver without the 'v' prefix that go module versions have
+fn semver(version: &'static str) -> &'static str {
+    version.trim_start_matches('v')
+}
+
+#[cfg(test)]
+mod tests {
+    use serde_json::{json, to_value};
+
+    use super::*;
+
+    const ADVISORIES: &[Advisory] = &[
+        Advisory {
+            id: "PGF-2026-0001",
+            published: "2026-10-14T00:00:00Z",
+            modified: "2026-10-15T00:00:00Z",
+            summary: "Wrong schema",
+            details: "JSONSchema describes the wrong thing.",
+            introduced: "v1.1.0",
+            fixed: Some("v1.2.0"),
+            symbols: &["JSONSchema"],
+            min_arity: family::MAX_ARITY - 1,
+        },
+        Advisory {
+            id: "PGF-2026-0002",
+            published: "2026-10-15T12:00:00Z",
+            modified: "2026-10-15T12:00:00Z",
+            summary: "Leaky interning",
+            details: "Intern never lets go.",
+            introduced: "v1.2.0",
+            fixed: None,
+            symbols: &["Intern", "Handle.Value"],
+            min_arity: family::MAX_ARITY,
+        },
+    ];
+
+    #[test]
+    fn db_index_is_as_new_as_the_newest_advisory() {
+        assert_eq!(
+            to_value(db_index_of(ADVISORIES)).unwrap(),
+            json!({ "modified": "2026-10-15T12:00:00Z" })
+        );
+        assert_eq!(
+            to_value(db_index_of(&[])).unwrap(),
+            json!({ "modified": "1970-01-01T00:00:00Z" })
+        );
+    }
+
+    #[test]
+    fn modules_index_lists_every_affected_arity() {
+        assert_eq!(
+            to_value(modules_index_of(ADVISORIES)).unwrap(),
+            json!([
+                {
+                    "path": "pkg.golang.fail/tuple/255/tuple",
+                    "vulns": [
+                        { "id": "PGF-2026-0001", "modified": "2026-10-15T00:00:00Z", "fixed": "1.2.0" },
+                    ],
+                },
+                {
+                    "path": "pkg.golang.fail/tuple/256/tuple",
+                    "vulns": [
+                        { "id": "PGF-2026-0001", "modified": "2026-10-15T00:00:00Z", "fixed": "1.2.0" },
+                        { "id": "PGF-2026-0002", "modified": "2026-10-15T12:00:00Z" },
+                    ],
+                },
+            ])
+        );
+        // from min_arity up, whether or not anything's been generated
+        let modules = modules_index_of(&ADVISORIES[..1]);
+        assert_eq!(modules.len(), 2);
+        assert_eq!(
+            modules_index_of(&[Advisory {
+                min_arity: 0,
+                ..ADVISORIES[0]
+            }])
+            .len() as u64,
+            family::MAX_ARITY + 1
+        );
+    }
+
+    #[test]
+    fn vulns_index_lists_every_advisory() {
+        assert_eq!(
+            to_value(vulns_index_of(ADVISORIES)).unwrap(),
+            json!([
+                { "id": "PGF-2026-0001", "modified": "2026-10-15T00:00:00Z", "aliases": [] },
+                { "id": "PGF-2026-0002", "modified": "2026-10-15T12:00:00Z", "aliases": [] },
+            ])
+        );
+    }
+
+    #[test]
+    fn entries() {
+        let affected = |n: u64| {
+            json!({
+                "package": {
+                    "name": format!("pkg.golang.fail/tuple/{}/tuple", n),
+                    "ecosystem": "Go",
+                },
+                "ranges": [{
+                    "type": "SEMVER",
+                    "events": [{ "introduced": "1.1.0" }, { "fixed": "1.2.0" }],
+                }],
+                "ecosystem_specific": {
+                    "imports": [{
+                        "path": format!("pkg.golang.fail/tuple/{}/tuple", n),
+                        "symbols": ["JSONSchema"],
+                    }],
+                },
+            })
+        };
+        assert_eq!(
+            to_value(entry_of(ADVISORIES, "PGF-2026-0001").unwrap()).unwrap(),
+            json!({
+                "schema_version": "1.3.1",
+                "id": "PGF-2026-0001",
+                "modified": "2026-10-15T00:00:00Z",
+                "published": "2026-10-14T00:00:00Z",
+                "summary": "Wrong schema",
+                "details": "JSONSchema describes the wrong thing.",
+                "affected": [affected(255), affected(256)],
+                "references": [{ "type": "WEB", "url": "https://pkg.golang.fail/tuple" }],
+            })
+        );
+
+        // without a fix, only the introducing release is listed
+        let unfixed = to_value(entry_of(ADVISORIES, "PGF-2026-0002").unwrap()).unwrap();
+        assert_eq!(
+            unfixed["affected"],
+            json!([{
+                "package": { "name": "pkg.golang.fail/tuple/256/tuple", "ecosystem": "Go" },
+                "ranges": [{ "type": "SEMVER", "events": [{ "introduced": "1.2.0" }] }],
+                "ecosystem_specific": {
+                    "imports": [{
+                        "path": "pkg.golang.fail/tuple/256/tuple",
+                        "symbols": ["Intern", "Handle.Value"],
+                    }],
+                },
+            }])
+        );
+
+        assert!(entry_of(ADVISORIES, "PGF-2026-0003").is_none());
+    }
+
+    #[test]
+    fn advisories_refer_to_releases() {
+        for advisory in super::ADVISORIES {
+            advisory.major();
+            assert!(advisory
+                .fixed
+                .map_or(true, |f| tuple::RELEASES.iter().any(|r| r.version == f)));
+        }
+    }
+}
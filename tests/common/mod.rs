@@ -0,0 +1,97 @@
+// Helpers for the integration tests, which run servers from the binary being tested.
+
+#![allow(dead_code)]
+
+use std::net::{SocketAddr, TcpStream};
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+use std::time::{Duration, Instant};
+
+// Server is a running server, killed when dropped.
+pub struct Server {
+    child: std::process::Child,
+    pub addr: SocketAddr,
+    log: PathBuf,
+}
+
+impl Server {
+    // start starts a server in dir, with the given environment variables set, logging its stdout to
+    // the file log in dir.
+    pub fn start(dir: &Path, log: &str, env: &[(&str, &str)]) -> Server {
+        let addr = std::net::TcpListener::bind("127.0.0.1:0")
+            .unwrap()
+            .local_addr()
+            .unwrap();
+        let mut command = Command::new(env!("CARGO_BIN_EXE_pkg-golang-fail"));
+        command
+            .current_dir(dir)
+            .env("PORT", addr.port().to_string())
+            .env_remove("UPSTREAM")
+            .stdout(std::fs::File::create(dir.join(log)).unwrap())
+            .stderr(Stdio::null());
+        for (key, value) in env {
+            command.env(key, value);
+        }
+        let server = Server {
+            child: command.spawn().unwrap(),
+            addr,
+            log: dir.join(log),
+        };
+
+        let deadline = Instant::now() + Duration::from_secs(10);
+        while TcpStream::connect(addr).is_err() {
+            assert!(
+                Instant::now() < deadline,
+                "server didn't start listening on {}",
+                addr
+            );
+            std::thread::sleep(Duration::from_millis(50));
+        }
+        server
+    }
+
+    pub fn url(&self) -> String {
+        format!("http://{}", self.addr)
+    }
+
+    // lines returns the lines the server has printed so far starting with prefix, without it.
+    pub fn lines(&self, prefix: &str) -> Vec<String> {
+        std::fs::read_to_string(&self.log)
+            .unwrap()
+            .lines()
+            .filter_map(|l| l.strip_prefix(prefix))
+            .map(str::to_string)
+            .collect()
+    }
+}
+
+impl Drop for Server {
+    fn drop(&mut self) {
+        let _ = self.child.kill();
+        let _ = self.child.wait();
+    }
+}
+
+// clone clones the repo at url into dir, returning whether git succeeded.
+pub fn clone(url: &str, dir: &Path) -> bool {
+    Command::new("git")
+        .args(["clone", "--quiet", "--bare"])
+        .arg(url)
+        .arg(dir)
+        .stderr(Stdio::null())
+        .status()
+        .unwrap()
+        .success()
+}
+
+// refs lists every branch and tag of the repo at dir, and what they point at.
+pub fn refs(dir: &Path) -> String {
+    let output = Command::new("git")
+        .arg("-C")
+        .arg(dir)
+        .arg("show-ref")
+        .output()
+        .unwrap();
+    assert!(output.status.success());
+    String::from_utf8(output.stdout).unwrap()
+}
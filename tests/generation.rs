@@ -0,0 +1,54 @@
+// Several servers sharing one repos directory.
+
+mod common;
+
+use std::sync::Barrier;
+
+use common::Server;
+
+#[test]
+fn servers_sharing_repos_generate_each_once() {
+    let (dir, clones) = (
+        tempfile::TempDir::new().unwrap(),
+        tempfile::TempDir::new().unwrap(),
+    );
+    let servers = (0..3)
+        .map(|i| Server::start(dir.path(), &format!("server{}.log", i), &[]))
+        .collect::<Vec<_>>();
+
+    // a few clones from each server, all at once, of a repo none of them has generated yet
+    let clones_per_server = 3;
+    let start = Barrier::new(servers.len() * clones_per_server);
+    std::thread::scope(|s| {
+        let mut threads = Vec::new();
+        for (i, server) in servers.iter().enumerate() {
+            for j in 0..clones_per_server {
+                let (start, into) = (&start, clones.path().join(format!("{}-{}", i, j)));
+                threads.push(s.spawn(move || {
+                    start.wait();
+                    common::clone(&format!("{}/tuple/7/tuple.git", server.url()), &into)
+                }));
+            }
+        }
+        for thread in threads {
+            assert!(thread.join().unwrap(), "clone failed");
+        }
+    });
+
+    let generated = servers
+        .iter()
+        .flat_map(|server| server.lines("generating: "))
+        .collect::<Vec<_>>();
+    assert_eq!(generated, ["tuple/7/tuple"]);
+
+    // and they all served the same repo
+    let refs = common::refs(&clones.path().join("0-0"));
+    for i in 0..servers.len() {
+        for j in 0..clones_per_server {
+            assert_eq!(
+                common::refs(&clones.path().join(format!("{}-{}", i, j))),
+                refs
+            );
+        }
+    }
+}
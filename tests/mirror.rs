@@ -0,0 +1,88 @@
+// Mirroring, with another local server as the upstream.
+
+mod common;
+
+use std::io::{Read, Write};
+use std::net::TcpListener;
+
+use common::Server;
+
+#[test]
+fn mirrors_repos_from_upstream() {
+    let (upstream_dir, mirror_dir, clones) = (
+        tempfile::TempDir::new().unwrap(),
+        tempfile::TempDir::new().unwrap(),
+        tempfile::TempDir::new().unwrap(),
+    );
+    let upstream = Server::start(upstream_dir.path(), "server.log", &[]);
+    let mirror = Server::start(
+        mirror_dir.path(),
+        "server.log",
+        &[("UPSTREAM", &upstream.url())],
+    );
+
+    let mirrored = clones.path().join("mirror");
+    assert!(common::clone(
+        &format!("{}/tuple/3/tuple.git", mirror.url()),
+        &mirrored
+    ));
+    let generated = clones.path().join("upstream");
+    assert!(common::clone(
+        &format!("{}/tuple/3/tuple.git", upstream.url()),
+        &generated
+    ));
+
+    assert_eq!(common::refs(&mirrored), common::refs(&generated));
+    assert_eq!(upstream.lines("generating: "), ["tuple/3/tuple"]);
+    assert_eq!(mirror.lines("mirrored: "), ["tuple/3/tuple"]);
+    assert!(mirror.lines("generating: ").is_empty());
+}
+
+#[test]
+fn generates_repos_when_upstream_is_unreachable() {
+    let (dir, clones) = (
+        tempfile::TempDir::new().unwrap(),
+        tempfile::TempDir::new().unwrap(),
+    );
+    // nothing listens on a port once its listener is dropped
+    let port = TcpListener::bind("127.0.0.1:0")
+        .unwrap()
+        .local_addr()
+        .unwrap()
+        .port();
+    let upstream = format!("http://127.0.0.1:{}", port);
+    let mirror = Server::start(dir.path(), "server.log", &[("UPSTREAM", &upstream)]);
+
+    assert!(common::clone(
+        &format!("{}/tuple/3/tuple.git", mirror.url()),
+        &clones.path().join("tuple")
+    ));
+    assert_eq!(mirror.lines("generating: "), ["tuple/3/tuple"]);
+}
+
+#[test]
+fn fails_when_upstream_fails() {
+    let (dir, clones) = (
+        tempfile::TempDir::new().unwrap(),
+        tempfile::TempDir::new().unwrap(),
+    );
+    // an upstream which is up, but fails every request
+    let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+    let upstream = format!("http://{}", listener.local_addr().unwrap());
+    std::thread::spawn(move || {
+        for mut stream in listener.incoming().flatten() {
+            let _ = stream.read(&mut [0; 4096]);
+            let _ = stream.write_all(
+                b"HTTP/1.1 500 Internal Server Error\r\nContent-Length: 0\r\nConnection: close\r\n\r\n",
+            );
+        }
+    });
+    let mirror = Server::start(dir.path(), "server.log", &[("UPSTREAM", &upstream)]);
+
+    assert!(!common::clone(
+        &format!("{}/tuple/3/tuple.git", mirror.url()),
+        &clones.path().join("tuple")
+    ));
+    assert!(mirror.lines("generating: ").is_empty());
+    assert!(mirror.lines("mirrored: ").is_empty());
+}